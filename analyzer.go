@@ -0,0 +1,294 @@
+package fzd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/char/asciifolding"
+	"github.com/blevesearch/bleve/v2/analysis/token/camelcase"
+	"github.com/blevesearch/bleve/v2/analysis/token/edgengram"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/token/ngram"
+	tokenizer "github.com/blevesearch/bleve/v2/analysis/tokenizer/regexp"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/registry"
+	"github.com/blevesearch/bleve/v2/search/highlight"
+	"github.com/blevesearch/bleve/v2/search/highlight/format/html"
+)
+
+// DefaultTokenizer is regexp of tokens used if not specified, which splits on non-word characters and underscores
+const DefaultTokenizer = `[^\W_]+`
+
+const (
+	customTokenizerName = "custom_tokenizer"
+	customAnalyzerName  = "custom_analyzer"
+	ngramFilterName     = "custom_ngram"
+	edgeNGramFilterName = "custom_edge_ngram"
+	digitsFilterName    = "fzd_digits"
+)
+
+func init() {
+	registry.RegisterTokenFilter(digitsFilterName, func(config map[string]interface{}, cache *registry.Cache) (analysis.TokenFilter, error) {
+		return digitsFilter{}, nil
+	})
+}
+
+// AnalyzerOptions of analyzer for paths, which is recorded with the index as it should be rebuilt once changed
+// Zero value only splits paths into tokens with DefaultTokenizer, same as index of older versions
+type AnalyzerOptions struct {
+	// Tokenizer is regexp of tokens, DefaultTokenizer is used if not specified
+	Tokenizer string `json:"tokenizer,omitempty"`
+
+	// CamelCase splits tokens on camelCase and PascalCase boundaries, i.e. MyComponent into My and Component
+	CamelCase bool `json:"camelCase,omitempty"`
+
+	// Digits splits tokens on boundaries between digits and other characters, i.e. v2 into v and 2
+	Digits bool `json:"digits,omitempty"`
+
+	// Lowercase tokens, such that searches are case insensitive
+	Lowercase bool `json:"lowercase,omitempty"`
+
+	// ASCIIFolding converts characters into their ASCII equivalents before tokenizing, i.e. café into cafe
+	ASCIIFolding bool `json:"asciiFolding,omitempty"`
+
+	// NGram replaces tokens with their n-grams within lengths, which is disabled if lengths are zero
+	NGram NGramOptions `json:"ngram,omitempty"`
+
+	// EdgeNGram replaces tokens with their prefixes within lengths, which is disabled if lengths are zero
+	EdgeNGram NGramOptions `json:"edgeNGram,omitempty"`
+}
+
+// NGramOptions of lengths of n-grams
+type NGramOptions struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+func (o NGramOptions) enabled() bool {
+	return o.Min != 0 || o.Max != 0
+}
+
+func (o NGramOptions) validate() error {
+	if o.Min < 1 || o.Max < o.Min {
+		return fmt.Errorf("lengths must be positive with min not greater than max: %v to %v", o.Min, o.Max)
+	}
+	return nil
+}
+
+// WithAnalyzer sets options of analyzer for newly built indexes
+// Indexes built with different options are incompatible, which should be indexed again from scratch
+func WithAnalyzer(analyzer AnalyzerOptions) IndexerOption {
+	return func(i *Indexer) {
+		i.analyzer = analyzer
+	}
+}
+
+// Validate options, such that invalid options are reported before indexing
+func (o AnalyzerOptions) Validate() error {
+	_, err := regexp.Compile(o.tokenizer())
+	if err != nil {
+		return fmt.Errorf("invalid tokenizer: %w", err)
+	}
+	if o.NGram.enabled() {
+		err = o.NGram.validate()
+		if err != nil {
+			return fmt.Errorf("invalid ngram: %w", err)
+		}
+	}
+	if o.EdgeNGram.enabled() {
+		err = o.EdgeNGram.validate()
+		if err != nil {
+			return fmt.Errorf("invalid edge ngram: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o AnalyzerOptions) tokenizer() string {
+	if o.Tokenizer == "" {
+		return DefaultTokenizer
+	}
+	return o.Tokenizer
+}
+
+// equal checks if analyzers are the same, where default tokenizer could be specified explicitly or not
+func (o AnalyzerOptions) equal(other AnalyzerOptions) bool {
+	o.Tokenizer = o.tokenizer()
+	other.Tokenizer = other.tokenizer()
+	return o == other
+}
+
+// addTo adds custom analyzer to mapping, and returns its name
+func (o AnalyzerOptions) addTo(m *mapping.IndexMappingImpl) (string, error) {
+	err := o.Validate()
+	if err != nil {
+		return "", err
+	}
+	err = m.AddCustomTokenizer(customTokenizerName, map[string]interface{}{
+		"type":   tokenizer.Name,
+		"regexp": o.tokenizer(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// folding is applied before tokenizing, as non-ASCII letters are not within \w of default tokenizer
+	// Locations are of the folded path, which are mapped back to the original path by unfoldLocations
+	var charFilters []string
+	if o.ASCIIFolding {
+		charFilters = append(charFilters, asciifolding.Name)
+	}
+	// case is needed for splitting camelCase, so tokens are lowercased afterwards
+	var tokenFilters []string
+	if o.CamelCase {
+		tokenFilters = append(tokenFilters, camelcase.Name)
+	}
+	if o.Digits {
+		tokenFilters = append(tokenFilters, digitsFilterName)
+	}
+	if o.Lowercase {
+		tokenFilters = append(tokenFilters, lowercase.Name)
+	}
+	if o.NGram.enabled() {
+		err = m.AddCustomTokenFilter(ngramFilterName, map[string]interface{}{
+			"type": ngram.Name,
+			"min":  float64(o.NGram.Min),
+			"max":  float64(o.NGram.Max),
+		})
+		if err != nil {
+			return "", err
+		}
+		tokenFilters = append(tokenFilters, ngramFilterName)
+	}
+	if o.EdgeNGram.enabled() {
+		err = m.AddCustomTokenFilter(edgeNGramFilterName, map[string]interface{}{
+			"type": edgengram.Name,
+			"back": false,
+			"min":  float64(o.EdgeNGram.Min),
+			"max":  float64(o.EdgeNGram.Max),
+		})
+		if err != nil {
+			return "", err
+		}
+		tokenFilters = append(tokenFilters, edgeNGramFilterName)
+	}
+
+	analyzer := map[string]interface{}{
+		"type":      custom.Name,
+		"tokenizer": customTokenizerName,
+	}
+	if len(charFilters) != 0 {
+		analyzer["char_filters"] = charFilters
+	}
+	if len(tokenFilters) != 0 {
+		analyzer["token_filters"] = tokenFilters
+	}
+	err = m.AddCustomAnalyzer(customAnalyzerName, analyzer)
+	if err != nil {
+		return "", err
+	}
+	return customAnalyzerName, nil
+}
+
+// normalize term of queries which are not analyzed, such that it matches tokens normalized by the analyzer
+func (o AnalyzerOptions) normalize(term string) string {
+	if o.ASCIIFolding {
+		term = string(asciifolding.New().Filter([]byte(term)))
+	}
+	if o.Lowercase {
+		term = strings.ToLower(term)
+	}
+	return term
+}
+
+// unfoldLocations of hits from byte offsets of paths folded into ASCII to those of the original paths,
+// as folded characters could be of different lengths, i.e. é of 2 bytes is folded into e of 1 byte
+// Fragments of path highlighted with folded offsets are rebuilt from the unfolded locations as well
+func unfoldLocations(res *bleve.SearchResult) {
+	for _, h := range res.Hits {
+		for field, terms := range h.Locations {
+			text := h.ID
+			if field == FieldName {
+				text = filepath.Base(h.ID)
+			}
+			offsets := newFoldedOffsets(text)
+			for _, locations := range terms {
+				for _, l := range locations {
+					l.Start, l.End = offsets.unfold(l.Start, l.End)
+				}
+			}
+		}
+		if _, ok := h.Fragments[FieldPath]; ok {
+			fragment := &highlight.Fragment{Orig: []byte(h.ID), End: len(h.ID)}
+			formatter := html.NewFragmentFormatter("<mark>", "</mark>")
+			h.Fragments[FieldPath] = []string{formatter.Format(fragment, highlight.OrderTermLocations(h.Locations[FieldPath]))}
+		}
+	}
+}
+
+// foldedOffsets maps each byte of text folded into ASCII to byte range of the original character folded from
+type foldedOffsets struct {
+	starts []uint64
+	ends   []uint64
+}
+
+func newFoldedOffsets(text string) foldedOffsets {
+	var o foldedOffsets
+	folding := asciifolding.New()
+	for i := 0; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		n := len(folding.Filter([]byte(text[i : i+size])))
+		for j := 0; j < n; j++ {
+			o.starts = append(o.starts, uint64(i))
+			o.ends = append(o.ends, uint64(i+size))
+		}
+		i += size
+	}
+	return o
+}
+
+// unfold byte range of folded text, where range out of the text is left as is
+func (o foldedOffsets) unfold(start uint64, end uint64) (uint64, uint64) {
+	if start >= end || end > uint64(len(o.starts)) {
+		return start, end
+	}
+	return o.starts[start], o.ends[end-1]
+}
+
+// digitsFilter splits tokens on boundaries between digits and other characters
+type digitsFilter struct{}
+
+func (digitsFilter) Filter(input analysis.TokenStream) analysis.TokenStream {
+	output := make(analysis.TokenStream, 0, len(input))
+	position := 1
+	for _, token := range input {
+		runes := bytes.Runes(token.Term)
+		start := 0
+		offset := token.Start
+		for n := 1; n <= len(runes); n++ {
+			if n < len(runes) && unicode.IsDigit(runes[n]) == unicode.IsDigit(runes[n-1]) {
+				continue
+			}
+			term := analysis.BuildTermFromRunes(runes[start:n])
+			output = append(output, &analysis.Token{
+				Term:     term,
+				Start:    offset,
+				End:      offset + len(term),
+				Position: position,
+				Type:     token.Type,
+			})
+			position++
+			offset += len(term)
+			start = n
+		}
+	}
+	return output
+}
@@ -0,0 +1,74 @@
+package fzd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func analyze(t *testing.T, analyzer AnalyzerOptions, text string) []string {
+	m, err := newIndexMapping(analyzer)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	tokens, err := m.AnalyzeText(m.DefaultAnalyzer, []byte(text))
+	assert.NoError(t, err)
+	var terms []string
+	for _, token := range tokens {
+		terms = append(terms, string(token.Term))
+	}
+	return terms
+}
+
+func TestAnalyzer(t *testing.T) {
+	tests := []struct {
+		name     string
+		analyzer AnalyzerOptions
+		text     string
+		expected []string
+	}{
+		{"default", AnalyzerOptions{}, "/home/MyComponent2_v.tsx", []string{"home", "MyComponent2", "v", "tsx"}},
+		{"camel case", AnalyzerOptions{CamelCase: true}, "/home/MyHTTPComponent.tsx", []string{"home", "My", "HTTP", "Component", "tsx"}},
+		{"camel case and lowercase", AnalyzerOptions{CamelCase: true, Lowercase: true}, "/MyComponent2", []string{"my", "component", "2"}},
+		{"digits", AnalyzerOptions{Digits: true}, "/file2name/v10", []string{"file", "2", "name", "v", "10"}},
+		{"lowercase", AnalyzerOptions{Lowercase: true}, "/Home/README.md", []string{"home", "readme", "md"}},
+		{"without ascii folding", AnalyzerOptions{}, "/café", []string{"caf"}},
+		{"ascii folding", AnalyzerOptions{ASCIIFolding: true}, "/café", []string{"cafe"}},
+		{"ngram", AnalyzerOptions{NGram: NGramOptions{Min: 2, Max: 2}}, "/abcd", []string{"ab", "bc", "cd"}},
+		{"edge ngram", AnalyzerOptions{EdgeNGram: NGramOptions{Min: 1, Max: 3}}, "/abcd", []string{"a", "ab", "abc"}},
+		{"custom tokenizer", AnalyzerOptions{Tokenizer: `[^/.]+`}, "/my_file.txt", []string{"my_file", "txt"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, analyze(t, test.analyzer, test.text))
+		})
+	}
+}
+
+func TestAnalyzerValidate(t *testing.T) {
+	assert.NoError(t, AnalyzerOptions{}.Validate())
+	assert.Error(t, AnalyzerOptions{Tokenizer: "["}.Validate())
+	assert.Error(t, AnalyzerOptions{NGram: NGramOptions{Min: 3, Max: 2}}.Validate())
+	assert.Error(t, AnalyzerOptions{EdgeNGram: NGramOptions{Max: 2}}.Validate())
+}
+
+func TestAnalyzerNormalize(t *testing.T) {
+	assert.Equal(t, "Café", AnalyzerOptions{}.normalize("Café"))
+	assert.Equal(t, "cafe", AnalyzerOptions{Lowercase: true, ASCIIFolding: true}.normalize("Café"))
+}
+
+func TestFoldedOffsetsUnfold(t *testing.T) {
+	// é is folded from 2 bytes into 1, and æ is folded from 2 bytes into 2 characters
+	text := "/café/æble"
+	offsets := newFoldedOffsets(text)
+	start, end := offsets.unfold(1, 5)
+	assert.Equal(t, "café", text[start:end])
+	start, end = offsets.unfold(6, 11)
+	assert.Equal(t, "æble", text[start:end])
+	start, end = offsets.unfold(6, 7)
+	assert.Equal(t, "æ", text[start:end])
+
+	start, end = offsets.unfold(11, 20)
+	assert.Equal(t, uint64(11), start)
+	assert.Equal(t, uint64(20), end)
+}
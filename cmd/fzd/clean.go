@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/horacehylee/fzd"
+	"github.com/urfave/cli/v2"
+)
+
+// clean removes index generations other than the current one, or all of them if specified by flag
+// Generations opened by others, i.e. running daemon, are kept, and removing all of them fails while any is in use
+func clean(ctx *cli.Context, indexer *fzd.Indexer) error {
+	all := ctx.Bool("all")
+	removed, err := indexer.Clean(all)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %v index generations\n", len(removed))
+	if all {
+		fmt.Println("Index has to be created again with \"fzd index\"")
+	}
+	return nil
+}
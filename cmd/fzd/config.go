@@ -1,40 +1,111 @@
 package main
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/horacehylee/fzd"
+	"github.com/horacehylee/fzd/server"
 	"github.com/spf13/viper"
 )
 
+// Policies of reindexing automatically before searching
+const (
+	// autoReindexNever leaves index as is, where user is prompted if index is not created yet
+	autoReindexNever = "never"
+
+	// autoReindexIfMissing creates index if it is not created yet, or could only be recovered by indexing from scratch
+	autoReindexIfMissing = "if-missing"
+
+	// autoReindexIfOlderThan also reindexes incrementally if index was last indexed before max age
+	autoReindexIfOlderThan = "if-older-than"
+)
+
 type config struct {
 	Index struct {
-		BasePath string
+		BasePath    string
+		Concurrency int
+		FailFast    bool
+		Retention   int
+		Analyzer    fzd.AnalyzerOptions
+		AutoReindex string
+		MaxAge      time.Duration
+	}
+	Search struct {
+		Limit     int
+		Fuzziness *int
+		Queries   []fzd.QueryKind
+		Boosts    map[fzd.QueryKind]float64
+		Sort      []string
+		Filters   map[string]string
+
+		FieldBoosts    map[string]float64
+		PathBonus      *float64
+		FrecencyWeight *float64
+	}
+	Serve struct {
+		Address string
 	}
 	Locations []struct {
 		Path    string
 		Filters []fzd.Filter
 		Ignores []interface{}
+
+		// Optional location could be missing, i.e. on removable drive, which is only warned by validation
+		Optional bool
 	}
 }
 
+// newConfig reads config, which fails if config is invalid
 func newConfig() (config, error) {
-	viper.SetConfigName(".fzd")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath(filepath.Join("$HOME", ".fzd"))
+	c, err := readConfig()
+	if err != nil {
+		return config{}, err
+	}
+	err = c.validate()
+	if err != nil {
+		return config{}, err
+	}
+	return c, nil
+}
 
-	viper.SetDefault("index.basepath", "$HOME/.fzd/indexes")
+// readConfig from config file merged with defaults and environment variables, without validating it
+// Config file is the one specified by --config flag or FZD_CONFIG if set, otherwise the first existing one of configPaths
+// Its format is of its extension, where file specified without supported extension is read as YAML
+func readConfig() (config, error) {
+	if viper.ConfigFileUsed() == "" {
+		for _, p := range configPaths() {
+			if file := p.file(); file != "" {
+				viper.SetConfigFile(file)
+				break
+			}
+		}
+	}
+	if !supportedConfigExt(viper.ConfigFileUsed()) {
+		viper.SetConfigType("yaml")
+	}
 
-	err := viper.ReadInConfig()
+	viper.SetDefault("index.basepath", defaultBasePath())
+	viper.SetDefault("index.retention", 3)
+	viper.SetDefault("index.autoreindex", autoReindexNever)
+	viper.SetDefault("search.limit", 5)
+
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	err := bindEnvs(reflect.TypeOf(config{}), "")
 	if err != nil {
-		var e *viper.ConfigFileNotFoundError
-		if !errors.As(err, &e) {
+		return config{}, err
+	}
+
+	if viper.ConfigFileUsed() != "" {
+		err = viper.ReadInConfig()
+		if err != nil {
 			return config{}, fmt.Errorf("could not read config: %w", err)
 		}
 	}
@@ -43,23 +114,222 @@ func newConfig() (config, error) {
 	if err != nil {
 		return config{}, err
 	}
+	return c, nil
+}
 
-	err = c.validate()
+// envPrefix of environment variables overriding config, i.e. FZD_SEARCH_LIMIT for search.limit
+const envPrefix = "fzd"
+
+// bindEnvs for keys of config, such that they are unmarshalled even if they are not specified in config file
+// Keys of maps and lists of objects are specified with JSON, i.e. FZD_SEARCH_FIELDBOOSTS='{"name": 3}'
+func bindEnvs(t reflect.Type, prefix string) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := strings.ToLower(f.Name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct:
+			err := bindEnvs(ft, key)
+			if err != nil {
+				return err
+			}
+			continue
+		case ft.Kind() == reflect.Map, ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			err := setJSONEnv(key)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := viper.BindEnv(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setJSONEnv overrides key with JSON value of its environment variable if it is set
+// Such keys could not be bound, as environment variables are only parsed into strings or comma separated lists
+func setJSONEnv(key string) error {
+	env := envName(key)
+	v := os.Getenv(env)
+	if v == "" {
+		return nil
+	}
+	var value interface{}
+	err := json.Unmarshal([]byte(v), &value)
 	if err != nil {
-		return config{}, err
+		return fmt.Errorf("invalid %v, which should be JSON for %v: %w", env, key, err)
 	}
+	viper.Set(key, value)
+	return nil
+}
 
-	return c, nil
+// overridingEnv returns environment variable set for key or its nearest parent, i.e. FZD_LOCATIONS for locations[0].path
+// It is empty if the key is not overridden by environment variable
+func overridingEnv(key string) string {
+	for key != "" {
+		if env := envName(key); os.Getenv(env) != "" {
+			return env
+		}
+		i := strings.LastIndexAny(key, ".[")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	return ""
+}
+
+// envName of config key, i.e. FZD_INDEX_BASEPATH for index.basePath
+func envName(key string) string {
+	return strings.ToUpper(envPrefix + "_" + strings.ReplaceAll(key, ".", "_"))
+}
+
+// configFileName of config file within working directory and $HOME/.fzd, without extension
+const configFileName = ".fzd"
+
+// xdgConfigFileName of config file within $XDG_CONFIG_HOME/fzd, which is not hidden as it is within its own directory
+const xdgConfigFileName = "config"
+
+// configPath to be looked up for config file, with description of where it comes from
+type configPath struct {
+	source string
+	dir    string
+	name   string
+}
+
+// file of config within the directory with any of extensions supported by viper, or empty if it is not found
+// Extensions are looked up in the same order as viper, i.e. .fzd.json before .fzd.yaml
+func (p configPath) file() string {
+	for _, ext := range viper.SupportedExts {
+		file := filepath.Join(p.dir, p.name+"."+ext)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// String of path with extensions, i.e. $HOME/.fzd/.fzd.{json,toml,yaml,...}
+func (p configPath) String() string {
+	return fmt.Sprintf("%v.{%v}", filepath.Join(p.dir, p.name), strings.Join(viper.SupportedExts, ","))
+}
+
+// configPaths in lookup order, where the first existing one is read
+// $XDG_CONFIG_HOME defaults to $HOME/.config as per XDG base directory specification
+func configPaths() []configPath {
+	return []configPath{
+		{source: "working directory", dir: absPathify("."), name: configFileName},
+		{source: "$XDG_CONFIG_HOME/fzd", dir: filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "fzd"), name: xdgConfigFileName},
+		{source: "$HOME/.fzd", dir: filepath.Join(userHomeDir(), ".fzd"), name: configFileName},
+	}
+}
+
+// configExt of config file without leading dot, which specifies its format
+func configExt(file string) string {
+	return strings.TrimPrefix(filepath.Ext(file), ".")
+}
 
-	// TODO: help user write an example config
-	// err = viper.SafeWriteConfig()
-	// if err != nil {
-	// 	var e viper.ConfigFileAlreadyExistsError
-	// 	if !errors.As(err, &e) {
-	// 		log.Fatal(fmt.Errorf("failed to write config: %w", err))
-	// 	}
-	// }
-	// return config
+func supportedConfigExt(file string) bool {
+	ext := configExt(file)
+	for _, e := range viper.SupportedExts {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// initConfigPath of config file written by "fzd config init", which is within $XDG_CONFIG_HOME/fzd only if it is set,
+// such that existing setups with $HOME/.fzd are not changed
+// Existing config file of any extension is returned, such that it is not shadowed by the one written
+func initConfigPath() string {
+	if file := viper.ConfigFileUsed(); file != "" {
+		return file
+	}
+	p := configPaths()[2]
+	if os.Getenv("XDG_CONFIG_HOME") != "" {
+		p = configPaths()[1]
+	}
+	if file := p.file(); file != "" {
+		return file
+	}
+	return filepath.Join(p.dir, p.name+".yaml")
+}
+
+// defaultBasePath of indexes, which is within $XDG_DATA_HOME/fzd only if it is set,
+// such that existing indexes within $HOME/.fzd are still used
+func defaultBasePath() string {
+	if os.Getenv("XDG_DATA_HOME") != "" {
+		return filepath.Join("$XDG_DATA_HOME", "fzd", "indexes")
+	}
+	return filepath.Join("$HOME", ".fzd", "indexes")
+}
+
+// socketDir of Unix socket of daemon, which is within $XDG_CACHE_HOME/fzd only if it is set, otherwise within base path
+// It allows base path to be on file systems not supporting Unix sockets, i.e. network drives
+func socketDir(cfg config) string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "fzd")
+	}
+	return cfg.Index.BasePath
+}
+
+// xdgDir specified by environment variable, or fallback within home directory if it is not set
+func xdgDir(env string, fallback string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	return filepath.Join(userHomeDir(), fallback)
+}
+
+// printConfigPaths in lookup order, with base path of indexes and socket of daemon
+func printConfigPaths(cfg config) {
+	fmt.Println("Config file, where the first one found is used:")
+	file := viper.ConfigFileUsed()
+	explicit := file != ""
+	paths := configPaths()
+	for _, p := range paths {
+		if p.file() == file {
+			explicit = false
+		}
+	}
+	if explicit {
+		fmt.Printf("  1. %v (used), from --config flag or FZD_CONFIG\n", file)
+	} else {
+		fmt.Println("  1. --config flag or FZD_CONFIG (not set)")
+	}
+	for i, p := range paths {
+		found := p.file()
+		switch {
+		case found == "":
+			fmt.Printf("  %v. %v (not found), from %v\n", i+2, p, p.source)
+		case !explicit && found == file:
+			fmt.Printf("  %v. %v (used), from %v\n", i+2, found, p.source)
+		default:
+			fmt.Printf("  %v. %v, from %v\n", i+2, found, p.source)
+		}
+	}
+	if file == "" {
+		fmt.Println("Config file is not found, defaults are used")
+	}
+	fmt.Println()
+	fmt.Printf("Index base path: %v\n", cfg.Index.BasePath)
+	fmt.Println("  from index.basePath, or $XDG_DATA_HOME/fzd/indexes if it is set, otherwise $HOME/.fzd/indexes")
+	fmt.Printf("Daemon socket: %v\n", filepath.Join(socketDir(cfg), server.SocketFileName))
+	fmt.Println("  within $XDG_CACHE_HOME/fzd if it is set, otherwise within index base path")
+	fmt.Println()
+	fmt.Printf("Config keys are overridden by environment variables, i.e. %v for search.limit\n", envName("search.limit"))
+	fmt.Printf("  where maps and lists of objects are JSON, i.e. %v='{\"name\": 3}' or %v='[{\"path\": \"$HOME/Projects\"}]'\n",
+		envName("search.fieldBoosts"), envName("locations"))
 }
 
 func (c *config) parse() error {
@@ -70,14 +340,55 @@ func (c *config) parse() error {
 
 	c.Index.BasePath = absPathify(c.Index.BasePath)
 	for i := range c.Locations {
-		c.Locations[i].Path = absPathify(c.Locations[i].Path)
+		// blank path is left as is to be reported by validation, instead of resolved into working directory
+		if c.Locations[i].Path != "" {
+			c.Locations[i].Path = absPathify(c.Locations[i].Path)
+		}
 	}
 	return nil
 }
 
-func (c *config) validate() error {
-	// TODO: check all mandatory fields
-	return nil
+// printConfigFile prints path of config file read, or notes that defaults are used if it is not found
+func printConfigFile() {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		fmt.Println("Config file is not found, defaults are used")
+		return
+	}
+	fmt.Println(file)
+}
+
+// searchOptions from config, where unspecified options are left as defaults
+func (c *config) searchOptions() fzd.SearchOptions {
+	opts := fzd.DefaultSearchOptions()
+	if c.Search.Limit != 0 {
+		opts.Limit = c.Search.Limit
+	}
+	if c.Search.Fuzziness != nil {
+		opts.Fuzziness = *c.Search.Fuzziness
+	}
+	if len(c.Search.Queries) != 0 {
+		opts.Queries = c.Search.Queries
+	}
+	for kind, boost := range c.Search.Boosts {
+		opts.Boosts[kind] = boost
+	}
+	if len(c.Search.Sort) != 0 {
+		opts.Sort = c.Search.Sort
+	}
+	if len(c.Search.Filters) != 0 {
+		opts.Filters = c.Search.Filters
+	}
+	for field, boost := range c.Search.FieldBoosts {
+		opts.FieldBoosts[field] = boost
+	}
+	if c.Search.PathBonus != nil {
+		opts.PathBonus = *c.Search.PathBonus
+	}
+	if c.Search.FrecencyWeight != nil {
+		opts.FrecencyWeight = *c.Search.FrecencyWeight
+	}
+	return opts
 }
 
 func userHomeDir() string {
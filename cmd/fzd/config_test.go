@@ -0,0 +1,92 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/horacehylee/fzd"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+// setEnvConfig without config file, such that config is specified by environment variables only
+func setEnvConfig(t *testing.T, env map[string]string) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+}
+
+func readEnvConfig(t *testing.T, env map[string]string) (config, error) {
+	setEnvConfig(t, env)
+	return readConfig()
+}
+
+func TestReadConfigOfMapsFromEnv(t *testing.T) {
+	c, err := readEnvConfig(t, map[string]string{
+		"FZD_SEARCH_FIELDBOOSTS": `{"name": 3, "path": 0.5}`,
+		"FZD_SEARCH_BOOSTS":      `{"fuzzy": 2}`,
+		"FZD_SEARCH_FILTERS":     `{"ext": "pdf"}`,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"name": 3, "path": 0.5}, c.Search.FieldBoosts)
+	assert.Equal(t, map[fzd.QueryKind]float64{fzd.Fuzzy: 2}, c.Search.Boosts)
+	assert.Equal(t, map[string]string{"ext": "pdf"}, c.Search.Filters)
+}
+
+func TestReadConfigOfLocationsFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	c, err := readEnvConfig(t, map[string]string{
+		"FZD_LOCATIONS": `[{"path": "` + filepath.ToSlash(dir) + `", "ignores": ["node_modules"]}]`,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, c.Locations, 1) {
+		assert.Equal(t, dir, c.Locations[0].Path)
+		assert.Equal(t, []interface{}{"node_modules"}, c.Locations[0].Ignores)
+	}
+}
+
+func TestReadConfigReturnsErrorIfEnvIsNotJSON(t *testing.T) {
+	_, err := readEnvConfig(t, map[string]string{
+		"FZD_SEARCH_FIELDBOOSTS": "name=3",
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "FZD_SEARCH_FIELDBOOSTS")
+	}
+}
+
+func TestOverridingEnv(t *testing.T) {
+	t.Setenv("FZD_LOCATIONS", `[{"path": "/tmp"}]`)
+	assert.Equal(t, "FZD_LOCATIONS", overridingEnv("locations[0].path"))
+	assert.Equal(t, "", overridingEnv("search.limit"))
+}
+
+func TestValidateConfigExitsWithErrorIfLocationIsMissing(t *testing.T) {
+	missing := filepath.ToSlash(filepath.Join(t.TempDir(), "missing"))
+	setEnvConfig(t, map[string]string{
+		"FZD_INDEX_BASEPATH": t.TempDir(),
+		"FZD_LOCATIONS":      `[{"path": "` + missing + `"}]`,
+	})
+	err := validateConfig()
+	var exit cli.ExitCoder
+	if assert.ErrorAs(t, err, &exit) {
+		assert.Equal(t, exitError, exit.ExitCode())
+	}
+
+	// optional location is only warned
+	viper.Reset()
+	t.Setenv("FZD_LOCATIONS", `[{"path": "`+missing+`", "optional": true}]`)
+	assert.NoError(t, validateConfig())
+}
+
+func TestRemovable(t *testing.T) {
+	assert.True(t, removable("/media/usb/Photos"))
+	assert.True(t, removable("/Volumes/Backup"))
+	assert.False(t, removable("/media"))
+	assert.False(t, removable("/home/Projects"))
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/viper"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ignoreSet is named set of ignore patterns to be chosen for config
+type ignoreSet struct {
+	Name     string
+	Usage    string
+	Patterns []string
+}
+
+var ignoreSets = []ignoreSet{
+	{
+		Name:  "default",
+		Usage: "dependencies, build outputs, logs and backups",
+		Patterns: []string{
+			"node_modules", "*.out", "build", ".git*", "[Tt]emp", "[Tt]mp", "*.log", "dist", "target",
+			"bin", "obj", "packages", "coverage", "*[Bb]ackup*", ".ipynb_checkpoints",
+		},
+	},
+	{
+		Name:     "minimal",
+		Usage:    "version control and dependencies only",
+		Patterns: []string{".git*", "node_modules"},
+	},
+	{
+		Name:  "none",
+		Usage: "index everything",
+	},
+}
+
+// defaultInitDirs within home directory to be indexed if they exist, which are picked without prompting
+var defaultInitDirs = []string{"Projects", "Documents", "Downloads", "Desktop"}
+
+// initConfig of locations and ignores picked for config file written by "fzd config init"
+type initConfig struct {
+	BasePath string
+
+	// Dirs are paths of locations relative to $HOME, such that config could be shared across machines
+	Dirs    []string
+	Ignores []string
+}
+
+// configTemplate of config file, where values are quoted as they could contain characters special to YAML, i.e. "#" or ": "
+var configTemplate = template.Must(template.New("config").Funcs(template.FuncMap{"quote": quoteYAML}).Parse(`# generated by "fzd config init", see .fzd.example.yaml for all options
+{{- if .Ignores}}
+default_ignores: &default_ignores
+{{- range .Ignores}}
+  - {{quote .}}
+{{- end}}
+{{- end}}
+
+index:
+  basePath: {{quote .BasePath}}
+  # number of index generations kept, including the current one, which could be rolled back to
+  retention: 3
+  # reindex before searching, where never prompts if index is not created yet (never, if-missing, if-older-than)
+  autoReindex: never
+
+search:
+  limit: 5
+
+locations:
+{{- range .Dirs}}
+  - path: {{quote .}}
+{{- if $.Ignores}}
+    ignores:
+      - *default_ignores
+{{- end}}
+{{- end}}
+`))
+
+// quoteYAML into double quoted scalar, which is escaped by YAML encoder
+func quoteYAML(s string) (string, error) {
+	b, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Style: yaml.DoubleQuotedStyle, Value: s})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// initConfigFile writes config file within home directory, which is never overwritten unless forced by flag
+// Locations and ignores are picked interactively if specified, otherwise existing default directories are picked
+func initConfigFile(ctx *cli.Context) error {
+	path := initConfigPath()
+	force := ctx.Bool("force")
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%v already exists, use --force to overwrite it", path)
+	}
+	if ext := configExt(path); supportedConfigExt(path) && ext != "yaml" && ext != "yml" {
+		return fmt.Errorf("%v is not YAML, which could only be written by removing it first", path)
+	}
+
+	var c initConfig
+	var err error
+	if ctx.Bool("interactive") {
+		if !canPrompt(ctx) {
+			return fmt.Errorf("interactive mode requires stdin to be a terminal")
+		}
+		c, err = pickInitConfig()
+	} else {
+		c, err = defaultInitConfig()
+	}
+	if err != nil {
+		return err
+	}
+	if len(c.Dirs) == 0 {
+		return fmt.Errorf("no directories are picked to be indexed")
+	}
+
+	var b strings.Builder
+	err = configTemplate.Execute(&b, c)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %v: %w", filepath.Dir(path), err)
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%v already exists, use --force to overwrite it", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(b.String())
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	fmt.Printf("Config is written to %v\n", path)
+	fmt.Println("Run \"fzd index\" to create index")
+	return nil
+}
+
+// defaultInitConfig picks default directories that exist, or the whole home directory if none of them exists
+func defaultInitConfig() (initConfig, error) {
+	home := userHomeDir()
+	var dirs []string
+	for _, d := range defaultInitDirs {
+		info, err := os.Stat(filepath.Join(home, d))
+		if err == nil && info.IsDir() {
+			dirs = append(dirs, "$HOME/"+d)
+		}
+	}
+	if len(dirs) == 0 {
+		dirs = []string{"$HOME"}
+	}
+	return initConfig{
+		BasePath: defaultBasePath(),
+		Dirs:     dirs,
+		Ignores:  ignoreSets[0].Patterns,
+	}, nil
+}
+
+// pickInitConfig prompts for each of non hidden directories within home directory, and set of ignores
+func pickInitConfig() (initConfig, error) {
+	home := userHomeDir()
+	entries, err := os.ReadDir(home)
+	if err != nil {
+		return initConfig{}, fmt.Errorf("failed to read %v: %w", home, err)
+	}
+	c := initConfig{
+		BasePath: defaultBasePath(),
+	}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if yesNo(fmt.Sprintf("Index %v", filepath.Join(home, e.Name()))) {
+			c.Dirs = append(c.Dirs, "$HOME/"+e.Name())
+		}
+	}
+
+	var items []string
+	for _, s := range ignoreSets {
+		items = append(items, fmt.Sprintf("%v (%v)", s.Name, s.Usage))
+	}
+	prompt := promptui.Select{
+		Label: "Ignores",
+		Items: items,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		return initConfig{}, err
+	}
+	c.Ignores = ignoreSets[i].Patterns
+	return c, nil
+}
+
+// showConfig prints effective config merged from config file and defaults
+func showConfig() error {
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	err := enc.Encode(viper.AllSettings())
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %w", err)
+	}
+	return enc.Close()
+}
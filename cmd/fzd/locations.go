@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// printLocations configured, where locations that do not exist are marked as missing
+func printLocations(cfg config) {
+	if len(cfg.Locations) == 0 {
+		fmt.Println("No locations are configured")
+		return
+	}
+	for _, l := range cfg.Locations {
+		missing := ""
+		if _, err := os.Stat(l.Path); err != nil {
+			missing = " (missing)"
+		}
+		fmt.Printf("%v%v\n", l.Path, missing)
+		if len(l.Filters) != 0 {
+			fmt.Printf("  filters: %v\n", l.Filters)
+		}
+		if n := countIgnores(l.Ignores); n != 0 {
+			fmt.Printf("  ignores: %v patterns\n", n)
+		}
+	}
+}
+
+// countIgnores of patterns, where lists of patterns could be nested, i.e. with YAML anchors
+func countIgnores(ignores []interface{}) int {
+	var n int
+	for _, i := range ignores {
+		if l, ok := i.([]interface{}); ok {
+			n += countIgnores(l)
+			continue
+		}
+		n++
+	}
+	return n
+}
@@ -1,88 +1,627 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/chzyer/readline"
 	"github.com/horacehylee/fzd"
+	"github.com/horacehylee/fzd/server"
 	"github.com/manifoldco/promptui"
+	"github.com/spf13/viper"
 	"github.com/urfave/cli/v2"
 )
 
+// Exit codes, such that scripts could tell missing index or no results apart from errors
+const (
+	exitError     = 1
+	exitNoResults = 2
+	exitNoIndex   = 3
+)
+
 func main() {
 	app := &cli.App{
-		Usage: "Golang file indexer and fuzzy file finder utiliy tool",
-		Flags: []cli.Flag{
-			&cli.IntFlag{
-				Name:    "num",
-				Aliases: []string{"n"},
-				Value:   5,
-				Usage:   "Number of results",
+		Usage:     "Golang file indexer and fuzzy file finder utiliy tool",
+		ArgsUsage: "[term...]",
+		Description: "Searches index for the term as a shortcut of search command, " +
+			"or shows status of index and prompts for reindex if term is not specified",
+		Before: func(ctx *cli.Context) error {
+			if file := ctx.String("config"); file != "" {
+				viper.SetConfigFile(absPathify(file))
+			}
+			return nil
+		},
+		Flags: append(append(append(searchFlags(),
+			&cli.BoolFlag{
+				Name:    "interactive",
+				Aliases: []string{"i"},
+				Usage:   "Pick from search results interactively as typing",
+			},
+			&cli.BoolFlag{
+				Name:  "full",
+				Usage: "Rebuild index from scratch instead of incremental reindex",
+			},
+			errorsFlag(),
+		), promptFlags()...),
+			&cli.StringFlag{
+				Name:    "config",
+				EnvVars: []string{"FZD_CONFIG"},
+				Usage:   "Path of config file instead of looking it up, see \"fzd config paths\" for lookup order",
+			},
+		),
+		Commands: []*cli.Command{
+			{
+				Name:      "search",
+				Usage:     "Search index for the term, without prompting if index is not created yet",
+				ArgsUsage: "[term...]",
+				Flags:     searchFlags(),
+				Action: func(ctx *cli.Context) error {
+					cfg, indexer, err := loadIndexer()
+					if err != nil {
+						return err
+					}
+					return search(ctx, cfg, indexer, false)
+				},
+			},
+			{
+				Name:      "pick",
+				Usage:     "Pick from search results interactively as typing",
+				ArgsUsage: "[term...]",
+				Flags:     append(searchFlags(), promptFlags()...),
+				Action: func(ctx *cli.Context) error {
+					cfg, indexer, err := loadIndexer()
+					if err != nil {
+						return err
+					}
+					return interactive(ctx, cfg, indexer)
+				},
+			},
+			{
+				Name:  "index",
+				Usage: "Reindex incrementally, or create index if it is not created yet",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "full",
+						Usage: "Rebuild index from scratch instead of incremental reindex",
+					},
+					&cli.StringSliceFlag{
+						Name:  "location",
+						Usage: "Location to be rebuilt, where sub-indexes of other locations are kept as is",
+					},
+					errorsFlag(),
+					noDaemonFlag(),
+				},
+				Action: func(ctx *cli.Context) error {
+					cfg, indexer, err := loadIndexer()
+					if err != nil {
+						return err
+					}
+					if client := daemon(ctx, cfg); client != nil {
+						return indexDaemon(ctx, client)
+					}
+					// generations beyond retention are removed on close
+					defer indexer.Close()
+					return indexOrReindex(ctx, indexer)
+				},
+			},
+			{
+				Name:  "status",
+				Usage: "Show name, last indexed time and number of files of current index",
+				Flags: []cli.Flag{
+					noDaemonFlag(),
+				},
+				Action: func(ctx *cli.Context) error {
+					cfg, indexer, err := loadIndexer()
+					if err != nil {
+						return err
+					}
+					return status(ctx, cfg, indexer)
+				},
+			},
+			{
+				Name:  "clean",
+				Usage: "Remove index generations other than the current one and those in use, i.e. opened by running daemon",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Remove all generations including the current one, which fails while any is in use, where history of selected paths is kept",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					_, indexer, err := loadIndexer()
+					if err != nil {
+						return err
+					}
+					return clean(ctx, indexer)
+				},
+			},
+			{
+				Name:  "config",
+				Usage: "Show path of config file in use",
+				Action: func(ctx *cli.Context) error {
+					// config is not validated, such that path of invalid config file could still be shown
+					_, err := readConfig()
+					if err != nil {
+						return err
+					}
+					printConfigFile()
+					return nil
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "init",
+						Usage: "Write starter config to $HOME/.fzd/.fzd.yaml, with existing common directories as locations",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "interactive",
+								Aliases: []string{"i"},
+								Usage:   "Pick directories within home directory to be indexed and set of ignores interactively",
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Overwrite existing config file",
+							},
+						},
+						Action: initConfigFile,
+					},
+					{
+						Name:  "show",
+						Usage: "Show effective config merged from config file and defaults",
+						Action: func(ctx *cli.Context) error {
+							_, err := readConfig()
+							if err != nil {
+								return err
+							}
+							return showConfig()
+						},
+					},
+					{
+						Name:  "paths",
+						Usage: "Show lookup order of config file, and paths of indexes and daemon socket",
+						Action: func(ctx *cli.Context) error {
+							cfg, err := readConfig()
+							if err != nil {
+								return err
+							}
+							printConfigPaths(cfg)
+							return nil
+						},
+					},
+					{
+						Name:  "validate",
+						Usage: "Validate config and report all problems found, including paths of locations and base path",
+						Action: func(ctx *cli.Context) error {
+							return validateConfig()
+						},
+					},
+				},
+			},
+			{
+				Name:  "locations",
+				Usage: "List configured locations with their filters and ignores",
+				Action: func(ctx *cli.Context) error {
+					cfg, err := newConfig()
+					if err != nil {
+						return err
+					}
+					printLocations(cfg)
+					return nil
+				},
+			},
+			{
+				Name:  "watch",
+				Usage: "Keep index up to date with file system events",
+				Flags: append(watchFlags(), createIndexFlag()),
+				Action: func(ctx *cli.Context) error {
+					cfg, indexer, err := loadIndexer()
+					if err != nil {
+						return err
+					}
+					return watch(ctx, cfg, indexer)
+				},
+			},
+			{
+				Name:  "serve",
+				Usage: "Run daemon keeping index opened, which serves search API over Unix socket or HTTP",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "addr",
+						Usage: "TCP address to listen on instead of Unix socket, i.e. 127.0.0.1:7070",
+					},
+					&cli.BoolFlag{
+						Name:  "allow-remote",
+						Usage: "Allow TCP address other than loopback, where API is served to others without authentication",
+					},
+					&cli.BoolFlag{
+						Name:  "watch",
+						Usage: "Keep index up to date with file system events while serving",
+					},
+				}, append(watchFlags(), createIndexFlag())...),
+				Action: func(ctx *cli.Context) error {
+					cfg, indexer, err := loadIndexer()
+					if err != nil {
+						return err
+					}
+					return serve(ctx, cfg, indexer)
+				},
+			},
+			{
+				Name:      "rollback",
+				Usage:     "Rollback index to specified generation, or the previous one if not specified",
+				ArgsUsage: "[name]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "list",
+						Aliases: []string{"l"},
+						Usage:   "List index generations kept, where the current one is marked with *",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					cfg, indexer, err := loadIndexer()
+					if err != nil {
+						return err
+					}
+					return rollback(ctx, cfg, indexer)
+				},
+			},
+			{
+				Name:  "history",
+				Usage: "Manage history of selected paths used for frecency ranking",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "record",
+						Usage:     "Record paths as selected",
+						ArgsUsage: "[path...]",
+						Action: func(ctx *cli.Context) error {
+							_, indexer, err := loadIndexer()
+							if err != nil {
+								return err
+							}
+							for _, path := range ctx.Args().Slice() {
+								err = indexer.RecordSelection(absPathify(path))
+								if err != nil {
+									return err
+								}
+							}
+							return nil
+						},
+					},
+					{
+						Name:  "prune",
+						Usage: "Remove selected paths that no longer exist",
+						Action: func(ctx *cli.Context) error {
+							_, indexer, err := loadIndexer()
+							if err != nil {
+								return err
+							}
+							removed, err := indexer.PruneHistory()
+							if err != nil {
+								return err
+							}
+							fmt.Printf("Pruned %v paths from history\n", removed)
+							return nil
+						},
+					},
+				},
 			},
 		},
 		Action: func(ctx *cli.Context) error {
-			cfg, err := newConfig()
-			if err != nil {
-				return err
-			}
-			indexer, err := newIndexer(cfg)
+			cfg, indexer, err := loadIndexer()
 			if err != nil {
 				return err
 			}
 
-			switch ctx.NArg() {
-			case 0:
-				return statusOrIndex(ctx, indexer)
-			case 1:
-				return search(ctx, cfg, indexer)
-			default:
-				return fmt.Errorf("too much arguments are passed: %v", ctx.Args())
+			if ctx.Bool("interactive") {
+				return interactive(ctx, cfg, indexer)
+			}
+			if ctx.NArg() == 0 {
+				return statusOrIndex(ctx, cfg, indexer)
 			}
+			// shortcut of search command, which prompts for creating index if it is not created yet
+			return search(ctx, cfg, indexer, true)
 		},
 	}
 
 	err := app.Run(os.Args)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+}
+
+// searchFlags for commands searching index, where a new slice is returned as flags could not be shared among commands
+func searchFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:    "num",
+			Aliases: []string{"n"},
+			Value:   5,
+			Usage:   "Number of results",
+		},
+		&cli.IntFlag{
+			Name:  "offset",
+			Usage: "Number of results to be skipped, i.e. for the next page of results",
+		},
+		&cli.IntFlag{
+			Name:  "fuzziness",
+			Value: fzd.DefaultFuzziness,
+			Usage: "Edit distance for fuzzy query",
+		},
+		&cli.StringSliceFlag{
+			Name:  "query",
+			Usage: "Kinds of queries to be combined (fuzzy, prefix, query_string, wildcard, match)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "boost",
+			Usage: "Boost of query kind as kind=boost, i.e. match=5",
+		},
+		&cli.StringSliceFlag{
+			Name:  "sort",
+			Usage: "Sort order of results by fields, prefixed with - for descending order, i.e. -modTime",
+		},
+		&cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "Filter of results as field=value, i.e. ext=pdf",
+		},
+		&cli.StringSliceFlag{
+			Name:  "field-boost",
+			Usage: "Boost of field searched as field=boost, i.e. name=3",
+		},
+		&cli.Float64Flag{
+			Name:  "path-bonus",
+			Value: fzd.DefaultPathBonus,
+			Usage: "Weight of bonuses for exact basename match, match at segment start and shallower depth, 0 to disable",
+		},
+		&cli.Float64Flag{
+			Name:  "frecency",
+			Value: fzd.DefaultFrecencyWeight,
+			Usage: "Weight of frecency of selected paths blended into ranking, from 0 to 1",
+		},
+		&cli.StringFlag{
+			Name:    "format",
+			Aliases: []string{"f"},
+			Value:   string(fzd.Plain),
+			Usage:   "Output format of results (plain, json, jsonl, null) or Go template, i.e. \"{{.Path}} {{.Score}}\"",
+		},
+		&cli.StringFlag{
+			Name:  "color",
+			Value: colorAuto,
+			Usage: "Highlight matched ranges of paths in plain output (auto, always, never)",
+		},
+		noDaemonFlag(),
+	}
+}
+
+// promptFlags for commands prompting for creating or reindexing index, where prompts are declined if stdin is not a terminal
+func promptFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "yes",
+			Aliases: []string{"y"},
+			Usage:   "Accept prompts for creating or reindexing index without prompting",
+		},
+		&cli.BoolFlag{
+			Name:  "no-prompt",
+			Usage: "Decline prompts without prompting, where it exits with status 3 if index is not created yet",
+		},
+	}
+}
+
+// createIndexFlag for long running commands, which never prompt but create index only if specified
+func createIndexFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:    "yes",
+		Aliases: []string{"y"},
+		Usage:   "Create index if it is not created yet, otherwise exits with status 3",
+	}
+}
+
+func errorsFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "errors",
+		Usage: "List all paths skipped due to errors while indexing",
+	}
+}
+
+func noDaemonFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "no-daemon",
+		Usage: "Open index directly instead of using running daemon",
 	}
 }
 
-func statusOrIndex(ctx *cli.Context, indexer *fzd.Indexer) error {
+func statusOrIndex(ctx *cli.Context, cfg config, indexer *fzd.Indexer) error {
+	if client := daemon(ctx, cfg); client != nil {
+		return statusOrReindexDaemon(ctx, client)
+	}
 	err := indexer.Open()
 	if err != nil {
-		return indexIfNotExists(indexer, err)
+		return indexIfNotExists(ctx, os.Stdout, indexer, err)
 	}
 	t, err := indexer.LastIndexed()
 	if err != nil {
 		return err
 	}
 	fmt.Printf("Index was last indexed at %v\n", t.Format("2006-01-02 15:04"))
-	yes := yesNo("Do you want to reindex it now")
-	if !yes {
+	if !confirm(ctx, "Do you want to reindex it now") {
+		return nil
+	}
+	if ctx.Bool("full") {
+		return index(ctx, os.Stdout, indexer)
+	}
+	return reindex(ctx, os.Stdout, indexer)
+}
+
+// statusOrReindexDaemon reindexes through daemon, as index is held opened by it
+func statusOrReindexDaemon(ctx *cli.Context, client *server.Client) error {
+	status, err := client.Status()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Index was last indexed at %v\n", status.LastIndexed.Format("2006-01-02 15:04"))
+	if !confirm(ctx, "Do you want to reindex it now") {
 		return nil
 	}
-	return index(indexer)
+	res, err := client.Reindex(ctx.Bool("full"))
+	if err != nil {
+		return err
+	}
+	printReindexResponse(ctx, os.Stdout, res)
+	return nil
+}
+
+// indexDaemon reindexes through daemon, as index is held opened by it
+// Index is rebuilt from scratch if full is specified, or only sub-indexes of locations if specified
+func indexDaemon(ctx *cli.Context, client *server.Client) error {
+	var res *server.ReindexResponse
+	var err error
+	if paths := locations(ctx); len(paths) != 0 {
+		res, err = client.Index(paths...)
+	} else {
+		res, err = client.Reindex(ctx.Bool("full"))
+	}
+	if err != nil {
+		return err
+	}
+	printReindexResponse(ctx, os.Stdout, res)
+	return nil
+}
+
+func printReindexResponse(ctx *cli.Context, w io.Writer, res *server.ReindexResponse) {
+	if res.Full {
+		fmt.Fprintf(w, "Indexed for %v files\n", res.DocCount)
+	} else {
+		fmt.Fprintf(w, "Reindexed with %v added, %v changed and %v removed files\n", res.Delta.Added, res.Delta.Changed, res.Delta.Removed)
+	}
+	printReport(ctx, w, res.Report)
+}
+
+// indexOrReindex creates index if it is not created yet or could not be opened, otherwise reindexes incrementally
+// Index is rebuilt from scratch if full is specified, or only sub-indexes of locations if specified
+func indexOrReindex(ctx *cli.Context, indexer *fzd.Indexer) error {
+	err := indexer.Open()
+	if needsIndex(err) {
+		return index(ctx, os.Stdout, indexer)
+	}
+	if err != nil {
+		return err
+	}
+	if ctx.Bool("full") || len(locations(ctx)) != 0 {
+		return index(ctx, os.Stdout, indexer)
+	}
+	return reindex(ctx, os.Stdout, indexer)
+}
+
+// autoReindex creates index if it could not be opened, or reindexes it if it is stale, as allowed by configured policy
+// Summary of indexing is written to stderr, such that it is not mixed with search results
+func autoReindex(ctx *cli.Context, cfg config, indexer *fzd.Indexer) error {
+	policy := cfg.Index.AutoReindex
+	err := indexer.Open()
+	if needsIndex(err) && policy != autoReindexNever {
+		return index(ctx, os.Stderr, indexer)
+	}
+	if err != nil || policy != autoReindexIfOlderThan {
+		return err
+	}
+	t, err := indexer.LastIndexed()
+	if err != nil {
+		return err
+	}
+	if time.Since(t) < cfg.Index.MaxAge {
+		return nil
+	}
+	return reindex(ctx, os.Stderr, indexer)
+}
+
+// autoReindexDaemon reindexes through daemon if index is stale, as allowed by configured policy
+func autoReindexDaemon(ctx *cli.Context, cfg config, client *server.Client) error {
+	if cfg.Index.AutoReindex != autoReindexIfOlderThan {
+		return nil
+	}
+	status, err := client.Status()
+	if err != nil {
+		return err
+	}
+	if time.Since(status.LastIndexed) < cfg.Index.MaxAge {
+		return nil
+	}
+	res, err := client.Reindex(false)
+	if err != nil {
+		return err
+	}
+	printReindexResponse(ctx, os.Stderr, res)
+	return nil
+}
+
+// locations to be rebuilt specified by flag, which are resolved the same as configured locations
+func locations(ctx *cli.Context) []string {
+	var paths []string
+	for _, path := range ctx.StringSlice("location") {
+		paths = append(paths, absPathify(path))
+	}
+	return paths
+}
+
+// needsIndex checks if index could not be opened as it is not created yet, or could only be recovered by indexing from scratch
+func needsIndex(err error) bool {
+	return errors.Is(err, fzd.ErrIndexHeadDoesNotExist) ||
+		errors.Is(err, fzd.ErrIndexHeadCorrupted) ||
+		errors.Is(err, fzd.ErrIndexIncompatible)
 }
 
-func indexIfNotExists(indexer *fzd.Indexer, err error) error {
+// noIndexError exits with status of no index, where error of opening index is kept as message
+func noIndexError(err error) error {
+	return cli.Exit(fmt.Sprintf("%v, run \"fzd index\" to create it", err), exitNoIndex)
+}
+
+// indexIfNotExists prompts for creating index if it could not be opened, or creates it without prompting if --yes is passed
+// It exits with status of no index if it is declined, or could not be prompted
+func indexIfNotExists(ctx *cli.Context, w io.Writer, indexer *fzd.Indexer, err error) error {
 	if err == nil {
 		return nil
 	}
-	if !errors.Is(err, fzd.ErrIndexHeadDoesNotExist) {
+	if !needsIndex(err) {
 		return err
 	}
-	fmt.Println("Index is not created yet")
+	if ctx.Bool("yes") {
+		return index(ctx, w, indexer)
+	}
+	if !canPrompt(ctx) {
+		return noIndexError(err)
+	}
+	if errors.Is(err, fzd.ErrIndexHeadDoesNotExist) {
+		fmt.Fprintln(w, "Index is not created yet")
+	} else {
+		// index could only be recovered by indexing from scratch
+		fmt.Fprintln(w, err)
+	}
 	yes := yesNo("Do you want to create it now")
 	if !yes {
-		return nil
+		return cli.Exit("", exitNoIndex)
 	}
-	return index(indexer)
+	return index(ctx, w, indexer)
 }
 
-func index(indexer *fzd.Indexer) error {
-	name, err := indexer.Index()
+func index(ctx *cli.Context, w io.Writer, indexer *fzd.Indexer) error {
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	l := newProgressLine("Indexing")
+	opts := l.options()
+	opts.Locations = locations(ctx)
+	name, report, err := indexer.IndexContext(c, opts)
+	l.clear()
+	if errors.Is(err, context.Canceled) {
+		return errCancelled
+	}
 	if err != nil {
 		return err
 	}
@@ -94,34 +633,302 @@ func index(indexer *fzd.Indexer) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Indexed for %v files\n", count)
+	fmt.Fprintf(w, "Indexed for %v files\n", count)
+	printReport(ctx, w, report)
 	return nil
 }
 
-func search(ctx *cli.Context, cfg config, indexer *fzd.Indexer) error {
-	term := ctx.Args().First()
-	if term == "" {
+func reindex(ctx *cli.Context, w io.Writer, indexer *fzd.Indexer) error {
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	l := newProgressLine("Reindexing")
+	delta, report, err := indexer.ReindexContext(c, l.options())
+	l.clear()
+	if errors.Is(err, fzd.ErrIndexManifestDoesNotExist) {
+		// index created without manifest could only be rebuilt from scratch
+		return index(ctx, w, indexer)
+	}
+	if errors.Is(err, context.Canceled) {
+		return errCancelled
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Reindexed with %v added, %v changed and %v removed files\n", delta.Added, delta.Changed, delta.Removed)
+	printReport(ctx, w, report)
+	return nil
+}
+
+// printReport prints summary of paths skipped due to errors, and lists all of them if requested by flag
+func printReport(ctx *cli.Context, w io.Writer, report *fzd.IndexReport) {
+	if report == nil || len(report.Errors) == 0 {
+		return
+	}
+	kinds := report.ErrorsByKind()
+	var counts []string
+	for kind, n := range kinds {
+		counts = append(counts, fmt.Sprintf("%v %v", n, kind))
+	}
+	sort.Strings(counts)
+	fmt.Fprintf(w, "Skipped %v paths due to errors (%v)\n", len(report.Errors), strings.Join(counts, ", "))
+	if !ctx.Bool("errors") {
+		fmt.Fprintln(w, "Use --errors to list all of them")
+		return
+	}
+	for _, e := range report.Errors {
+		fmt.Fprintf(w, "  [%v] %v\n", e.Kind, e)
+	}
+}
+
+// search for words of arguments joined as the term, where it prompts for creating index if it is not created yet only if specified
+func search(ctx *cli.Context, cfg config, indexer *fzd.Indexer, prompt bool) error {
+	input := strings.Join(ctx.Args().Slice(), " ")
+	if strings.TrimSpace(input) == "" {
 		return fmt.Errorf("term cannot be blank")
 	}
-	err := indexer.Open()
+	searchHits, err := newSearchHitsFunc(ctx, cfg, indexer, prompt)
 	if err != nil {
-		err = indexIfNotExists(indexer, err)
-		if err != nil {
-			return err
+		return err
+	}
+	opts, err := searchOptions(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	// term is left blank if only filters are specified, which matches all paths with filters
+	term, err := fzd.ParseQuery(input, &opts)
+	if err != nil {
+		return err
+	}
+	format := ctx.String("format")
+	formatter, err := fzd.NewFormatter(format)
+	if err != nil {
+		return err
+	}
+	if fzd.FormatNeedsFields(format) {
+		opts.Fields = []string{"*"}
+		opts.Highlight = true
+	}
+	color, err := useColor(ctx)
+	if err != nil {
+		return err
+	}
+	if color && fzd.Format(format) == fzd.Plain {
+		formatter = fzd.NewHighlightFormatter(ansiHighlight, ansiHighlightEnd)
+		opts.Highlight = true
+	}
+	hits, total, err := searchHits(term, opts)
+	if err != nil {
+		return err
+	}
+	err = formatter.Format(os.Stdout, hits)
+	if err != nil {
+		return err
+	}
+	if len(hits) == 0 {
+		return cli.Exit("", exitNoResults)
+	}
+	printTotal(opts.Offset, len(hits), total)
+	return nil
+}
+
+// printTotal prints range of results shown out of total to stderr if there are more results, only if stdout is a terminal
+// such that output of scripts is not mixed with it
+func printTotal(offset int, count int, total uint64) {
+	if count == 0 || uint64(offset+count) >= total || !readline.IsTerminal(int(os.Stdout.Fd())) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Showing %v-%v of %v results, use --offset %v for more\n", offset+1, offset+count, total, offset+count)
+}
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// useColor checks if output should be colored, where auto colors only if stdout is a terminal and NO_COLOR is not set
+func useColor(ctx *cli.Context) (bool, error) {
+	switch ctx.String("color") {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto:
+		if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			return false, nil
 		}
+		return readline.IsTerminal(int(os.Stdout.Fd())), nil
+	default:
+		return false, fmt.Errorf("\"%v\" color is not supported, should be one of auto, always or never", ctx.String("color"))
+	}
+}
+
+func interactive(ctx *cli.Context, cfg config, indexer *fzd.Indexer) error {
+	searchHits, err := newSearchHitsFunc(ctx, cfg, indexer, true)
+	if err != nil {
+		return err
 	}
-	res, err := indexer.Search(term)
+	opts, err := searchOptions(ctx, cfg)
 	if err != nil {
 		return err
 	}
-	num := ctx.Int("num")
-	for i, h := range res.Hits {
-		if i >= num {
-			break
+
+	path, err := pick(newPickSearchFunc(searchHits, opts), strings.Join(ctx.Args().Slice(), " "))
+	if errors.Is(err, errPickCancelled) {
+		// same exit code as interrupted by Ctrl-C
+		return cli.Exit("", 130)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(path)
+	return indexer.RecordSelection(path)
+}
+
+// newPickSearchFunc parses filters of each query typed into picker, and searches with highlight of matched ranges
+func newPickSearchFunc(searchHits searchHitsFunc, opts fzd.SearchOptions) searchFunc {
+	return func(input string, limit int) ([]fzd.Hit, uint64, error) {
+		// filters are parsed on each query, so options are copied to not accumulate filters
+		o := opts
+		o.Limit = limit
+		o.Highlight = true
+		term, err := fzd.ParseQuery(input, &o)
+		if err != nil {
+			return nil, 0, err
+		}
+		return searchHits(term, o)
+	}
+}
+
+// searchHitsFunc returns hits and total number of hits of the term
+type searchHitsFunc func(term string, opts fzd.SearchOptions) ([]fzd.Hit, uint64, error)
+
+// newSearchHitsFunc searches through daemon if it is running, otherwise opens index directly
+// Index is reindexed before searching as allowed by auto reindex policy, and if index could still not be opened,
+// it prompts for creating index only if specified, otherwise exits with status of no index
+func newSearchHitsFunc(ctx *cli.Context, cfg config, indexer *fzd.Indexer, prompt bool) (searchHitsFunc, error) {
+	if client := daemon(ctx, cfg); client != nil {
+		err := autoReindexDaemon(ctx, cfg, client)
+		if err != nil {
+			return nil, err
 		}
-		fmt.Printf("%v\n", h.ID)
+		return func(term string, opts fzd.SearchOptions) ([]fzd.Hit, uint64, error) {
+			res, err := client.Search(term, opts)
+			if err != nil {
+				return nil, 0, err
+			}
+			return res.Hits, res.Total, nil
+		}, nil
 	}
-	return nil
+	err := autoReindex(ctx, cfg, indexer)
+	if err != nil && !prompt {
+		if needsIndex(err) {
+			return nil, noIndexError(err)
+		}
+		return nil, err
+	}
+	if err != nil {
+		// messages are written to stderr, such that stdout could be captured for results
+		err = indexIfNotExists(ctx, os.Stderr, indexer, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return func(term string, opts fzd.SearchOptions) ([]fzd.Hit, uint64, error) {
+		res, err := indexer.SearchWithOptions(term, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return fzd.NewHits(res), res.Total, nil
+	}, nil
+}
+
+// searchOptions from config, which are overridden by flags if specified
+func searchOptions(ctx *cli.Context, cfg config) (fzd.SearchOptions, error) {
+	opts := cfg.searchOptions()
+	if ctx.IsSet("num") {
+		opts.Limit = ctx.Int("num")
+	}
+	if ctx.IsSet("offset") {
+		opts.Offset = ctx.Int("offset")
+	}
+	if ctx.IsSet("fuzziness") {
+		opts.Fuzziness = ctx.Int("fuzziness")
+	}
+	if ctx.IsSet("query") {
+		opts.Queries = nil
+		for _, kind := range ctx.StringSlice("query") {
+			opts.Queries = append(opts.Queries, fzd.QueryKind(kind))
+		}
+	}
+	for _, b := range ctx.StringSlice("boost") {
+		kind, value, err := splitKeyValue(b)
+		if err != nil {
+			return fzd.SearchOptions{}, err
+		}
+		boost, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fzd.SearchOptions{}, fmt.Errorf("invalid boost \"%v\": %w", b, err)
+		}
+		opts.Boosts[fzd.QueryKind(kind)] = boost
+	}
+	for _, b := range ctx.StringSlice("field-boost") {
+		field, value, err := splitKeyValue(b)
+		if err != nil {
+			return fzd.SearchOptions{}, err
+		}
+		boost, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fzd.SearchOptions{}, fmt.Errorf("invalid field boost \"%v\": %w", b, err)
+		}
+		opts.FieldBoosts[field] = boost
+	}
+	if ctx.IsSet("path-bonus") {
+		opts.PathBonus = ctx.Float64("path-bonus")
+	}
+	if ctx.IsSet("sort") {
+		opts.Sort = ctx.StringSlice("sort")
+	}
+	if ctx.IsSet("frecency") {
+		opts.FrecencyWeight = ctx.Float64("frecency")
+	}
+	if ctx.IsSet("filter") {
+		opts.Filters = make(map[string]string)
+		for _, f := range ctx.StringSlice("filter") {
+			field, value, err := splitKeyValue(f)
+			if err != nil {
+				return fzd.SearchOptions{}, err
+			}
+			opts.Filters[field] = value
+		}
+	}
+	return opts, nil
+}
+
+func splitKeyValue(s string) (string, string, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("\"%v\" should be in key=value format", s)
+	}
+	return parts[0], parts[1], nil
+}
+
+// canPrompt checks if user could be prompted, where stdin has to be a terminal and prompts are not disabled by flag
+func canPrompt(ctx *cli.Context) bool {
+	return !ctx.Bool("no-prompt") && readline.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// confirm prompts user with message, which is accepted without prompting if --yes is passed, or declined if user could not be prompted
+func confirm(ctx *cli.Context, msg string) bool {
+	if ctx.Bool("yes") {
+		return true
+	}
+	if !canPrompt(ctx) {
+		return false
+	}
+	return yesNo(msg)
 }
 
 func yesNo(msg string) bool {
@@ -133,6 +940,18 @@ func yesNo(msg string) bool {
 	return err == nil
 }
 
+func loadIndexer() (config, *fzd.Indexer, error) {
+	cfg, err := newConfig()
+	if err != nil {
+		return config{}, nil, err
+	}
+	indexer, err := newIndexer(cfg)
+	if err != nil {
+		return config{}, nil, err
+	}
+	return cfg, indexer, nil
+}
+
 func newIndexer(c config) (*fzd.Indexer, error) {
 	var options []fzd.IndexerOption
 	for _, l := range c.Locations {
@@ -142,5 +961,13 @@ func newIndexer(c config) (*fzd.Indexer, error) {
 		}
 		options = append(options, fzd.WithLocation(l.Path, locationOption))
 	}
+	if c.Index.Concurrency > 0 {
+		options = append(options, fzd.WithConcurrency(c.Index.Concurrency))
+	}
+	if c.Index.Retention > 0 {
+		options = append(options, fzd.WithRetention(c.Index.Retention))
+	}
+	options = append(options, fzd.WithFailFast(c.Index.FailFast))
+	options = append(options, fzd.WithAnalyzer(c.Index.Analyzer))
 	return fzd.NewIndexer(c.Index.BasePath, options...)
 }
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/chzyer/readline"
+	"github.com/horacehylee/fzd"
+)
+
+const (
+	ansiAltScreen    = "\x1b[?1049h"
+	ansiMainScreen   = "\x1b[?1049l"
+	ansiClearScreen  = "\x1b[H\x1b[2J"
+	ansiReverse      = "\x1b[7m"
+	ansiReset        = "\x1b[0m"
+	ansiHighlight    = "\x1b[1;33m"
+	ansiHighlightEnd = "\x1b[22;39m"
+	ansiFaint        = "\x1b[2m"
+)
+
+// errPickCancelled is returned if picker is cancelled without selection
+var errPickCancelled = errors.New("pick is cancelled")
+
+// searchFunc returns hits and total number of hits of the term, with at most limit number of hits
+// Hits should be searched with highlight, such that matched ranges of their paths are highlighted
+type searchFunc func(term string, limit int) ([]fzd.Hit, uint64, error)
+
+// picker is full screen interactive picker, which searches on every key stroke
+type picker struct {
+	search searchFunc
+	in     *bufio.Reader
+	out    io.Writer
+	width  int
+	height int
+
+	query  []rune
+	hits   []fzd.Hit
+	total  uint64
+	cursor int
+	err    error
+}
+
+// pick opens picker in terminal with initial query, and returns selected path
+// Picker is rendered to stderr, such that stdout could be captured for the selected path
+func pick(search searchFunc, query string) (string, error) {
+	stdin := int(os.Stdin.Fd())
+	if !readline.IsTerminal(stdin) {
+		return "", fmt.Errorf("interactive mode requires stdin to be a terminal")
+	}
+	width, height, err := readline.GetSize(int(os.Stderr.Fd()))
+	if err != nil {
+		return "", fmt.Errorf("failed to get terminal size: %w", err)
+	}
+
+	state, err := readline.MakeRaw(stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to set terminal to raw mode: %w", err)
+	}
+	defer readline.Restore(stdin, state)
+
+	fmt.Fprint(os.Stderr, ansiAltScreen)
+	defer fmt.Fprint(os.Stderr, ansiMainScreen)
+
+	p := &picker{
+		search: search,
+		in:     bufio.NewReader(os.Stdin),
+		out:    os.Stderr,
+		width:  width,
+		height: height,
+		query:  []rune(query),
+	}
+	return p.run()
+}
+
+func (p *picker) run() (string, error) {
+	p.update()
+	for {
+		p.render()
+
+		r, _, err := p.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case readline.CharEnter, readline.CharCtrlJ:
+			if len(p.hits) == 0 {
+				continue
+			}
+			return p.hits[p.cursor].Path, nil
+		case readline.CharInterrupt, readline.CharDelete:
+			return "", errPickCancelled
+		case readline.CharEsc:
+			if p.in.Buffered() == 0 {
+				return "", errPickCancelled
+			}
+			p.escape()
+		case readline.CharPrev, readline.CharKill:
+			p.move(-1)
+		case readline.CharNext, readline.CharTab:
+			p.move(1)
+		case readline.CharBackspace, readline.CharCtrlH:
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.update()
+			}
+		case readline.CharCtrlU:
+			p.query = nil
+			p.update()
+		case readline.CharCtrlW:
+			p.query = []rune(strings.TrimRightFunc(strings.TrimRightFunc(string(p.query), unicode.IsSpace), isWordRune))
+			p.update()
+		default:
+			if readline.IsPrintable(r) && r != readline.CharBackspace {
+				p.query = append(p.query, r)
+				p.update()
+			}
+		}
+	}
+}
+
+// escape handles escape sequences of arrow keys
+func (p *picker) escape() {
+	r, _, err := p.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return
+	}
+	r, _, err = p.in.ReadRune()
+	if err != nil {
+		return
+	}
+	switch r {
+	case 'A':
+		p.move(-1)
+	case 'B':
+		p.move(1)
+	}
+}
+
+func (p *picker) move(delta int) {
+	p.cursor += delta
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	if p.cursor >= len(p.hits) {
+		p.cursor = len(p.hits) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+func (p *picker) rows() int {
+	// lines for prompt and status are reserved
+	rows := p.height - 2
+	if rows < 1 {
+		rows = 1
+	}
+	return rows
+}
+
+func (p *picker) update() {
+	p.cursor = 0
+	p.hits = nil
+	p.total = 0
+	p.err = nil
+
+	term := strings.TrimSpace(string(p.query))
+	if term == "" {
+		return
+	}
+	p.hits, p.total, p.err = p.search(term, p.rows())
+}
+
+func (p *picker) render() {
+	var b strings.Builder
+	b.WriteString(ansiClearScreen)
+	b.WriteString("> ")
+	b.WriteString(string(p.query))
+	b.WriteString("\r\n")
+
+	b.WriteString(ansiFaint)
+	if p.err != nil {
+		b.WriteString(truncateLeft(p.err.Error(), p.width))
+	} else {
+		fmt.Fprintf(&b, "  %v/%v", len(p.hits), p.total)
+	}
+	b.WriteString(ansiReset)
+
+	for i, hit := range p.hits {
+		b.WriteString("\r\n")
+		if i == p.cursor {
+			b.WriteString(ansiReverse)
+			b.WriteString("> ")
+		} else {
+			b.WriteString("  ")
+		}
+		b.WriteString(highlightLine(hit, p.width-2))
+		b.WriteString(ansiReset)
+	}
+
+	// place cursor at the end of prompt
+	fmt.Fprintf(&b, "\x1b[1;%vH", len("> ")+len(p.query)+1)
+	fmt.Fprint(p.out, b.String())
+}
+
+// truncateLeft keeps the end of s within width, as base name is more relevant than parent directories
+func truncateLeft(s string, width int) string {
+	runes := []rune(s)
+	if width <= 1 || len(runes) <= width {
+		return s
+	}
+	return "…" + string(runes[len(runes)-width+1:])
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// highlightLine of hit truncated from left as truncateLeft does, where matched ranges of path are wrapped with highlight color
+// Ranges are matched by the index, such that fuzzy matches are highlighted while filters of query are not
+func highlightLine(hit fzd.Hit, width int) string {
+	line := truncateLeft(hit.Path, width)
+	if line == hit.Path {
+		return hit.Highlight(ansiHighlight, ansiHighlightEnd)
+	}
+	// ranges are cut off along with the start of path
+	start := len(hit.Path) - len(strings.TrimPrefix(line, "…"))
+	truncated := fzd.Hit{Document: fzd.Document{Path: hit.Path[start:]}}
+	for _, m := range hit.Matches {
+		if m.End <= start {
+			continue
+		}
+		if m.Start < start {
+			m.Start = start
+		}
+		truncated.Matches = append(truncated.Matches, fzd.MatchRange{Start: m.Start - start, End: m.End - start})
+	}
+	return "…" + truncated.Highlight(ansiHighlight, ansiHighlightEnd)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/horacehylee/fzd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickerHighlightsFuzzyMatches(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "report.txt")
+	err := os.WriteFile(file, []byte("content"), 0600)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(root, "notes.md"), []byte("content"), 0600)
+	assert.NoError(t, err)
+
+	indexer, err := fzd.NewIndexer(t.TempDir(), fzd.WithLocation(root, fzd.LocationOption{}))
+	assert.NoError(t, err)
+	defer indexer.Close()
+	name, err := indexer.Index()
+	assert.NoError(t, err)
+	err = indexer.OpenAndSwap(name)
+	assert.NoError(t, err)
+
+	searchHits := func(term string, opts fzd.SearchOptions) ([]fzd.Hit, uint64, error) {
+		res, err := indexer.SearchWithOptions(term, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return fzd.NewHits(res), res.Total, nil
+	}
+	var out strings.Builder
+	p := &picker{
+		search: newPickSearchFunc(searchHits, fzd.DefaultSearchOptions()),
+		out:    &out,
+		width:  200,
+		height: 10,
+		query:  []rune("ext:txt reprot"),
+	}
+	p.update()
+	assert.NoError(t, p.err)
+	if assert.Len(t, p.hits, 1) {
+		assert.Equal(t, file, p.hits[0].Path)
+	}
+
+	p.render()
+	assert.Contains(t, out.String(), ansiHighlight+"report"+ansiHighlightEnd)
+	// filters of query are not matched within path
+	assert.NotContains(t, out.String(), ansiHighlight+"txt")
+	assert.NotContains(t, out.String(), ansiHighlight+"ext")
+}
+
+func TestHighlightLineTruncated(t *testing.T) {
+	hit := fzd.Hit{
+		Document: fzd.Document{Path: "/home/report.txt"},
+		Matches:  []fzd.MatchRange{{Start: 1, End: 5}, {Start: 6, End: 12}},
+	}
+	mark := func(s string) string {
+		return ansiHighlight + s + ansiHighlightEnd
+	}
+	assert.Equal(t, "/"+mark("home")+"/"+mark("report")+".txt", highlightLine(hit, 80))
+	// ranges cut off along with the start of path are dropped or clipped
+	assert.Equal(t, "…"+mark("eport")+".txt", highlightLine(hit, 10))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/horacehylee/fzd"
+	"github.com/urfave/cli/v2"
+)
+
+// errCancelled exits with conventional status of interrupted by SIGINT
+var errCancelled = cli.Exit("Indexing is cancelled, index is left unchanged", 130)
+
+// progressLine renders progress of indexing on a single line of stderr, which is rewritten in place
+type progressLine struct {
+	action   string
+	terminal bool
+}
+
+func newProgressLine(action string) *progressLine {
+	return &progressLine{
+		action:   action,
+		terminal: readline.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+// options for reporting progress, where progress is not rendered if stderr is not a terminal, i.e. piped to a file
+func (l *progressLine) options() fzd.IndexOptions {
+	if !l.terminal {
+		return fzd.IndexOptions{}
+	}
+	return fzd.IndexOptions{
+		Progress: l.render,
+	}
+}
+
+func (l *progressLine) render(p fzd.Progress) {
+	fmt.Fprintf(os.Stderr, "\r\x1b[K%v %v: %v dirs, %v files (%v)", l.action, p.Location, p.Dirs, p.Files, p.Elapsed.Truncate(time.Second))
+}
+
+// clear the rendered line, such that results are printed from the start of line
+func (l *progressLine) clear() {
+	if l.terminal {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+	}
+}
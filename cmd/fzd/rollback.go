@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/horacehylee/fzd"
+	"github.com/urfave/cli/v2"
+)
+
+// rollback swaps to specified generation, or the previous one if not specified, through daemon if it is running
+func rollback(ctx *cli.Context, cfg config, indexer *fzd.Indexer) error {
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("too much arguments are passed: %v", ctx.Args())
+	}
+	name := ctx.Args().First()
+	client := daemon(ctx, cfg)
+
+	if ctx.Bool("list") {
+		var gens []fzd.Generation
+		var err error
+		if client != nil {
+			gens, err = client.Generations()
+		} else {
+			gens, err = indexer.Generations()
+		}
+		if err != nil {
+			return err
+		}
+		printGenerations(gens)
+		return nil
+	}
+
+	if client != nil {
+		res, err := client.Rollback(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back to %v with %v files\n", res.IndexName, res.DocCount)
+		return nil
+	}
+
+	name, err := indexer.Rollback(name)
+	if err != nil {
+		return err
+	}
+	// generations beyond retention are removed on close
+	defer indexer.Close()
+	count, err := indexer.DocCount()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rolled back to %v with %v files\n", name, count)
+	return nil
+}
+
+func printGenerations(gens []fzd.Generation) {
+	if len(gens) == 0 {
+		fmt.Println("Index is not created yet")
+		return
+	}
+	for _, g := range gens {
+		current := " "
+		if g.Current {
+			current = "*"
+		}
+		modified := ""
+		if g.Modified != nil {
+			modified = ", modified by reindex of others"
+		}
+		fmt.Printf("%v %v  %v  %v files%v\n", current, g.Name, g.Created.Format("2006-01-02 15:04"), g.DocCount, modified)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/horacehylee/fzd"
+	"github.com/horacehylee/fzd/server"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// daemonPingTimeout is kept short, as CLI falls back to open index by itself if daemon is not reachable
+	daemonPingTimeout = 200 * time.Millisecond
+
+	// shutdownTimeout for in-flight requests to be completed when daemon is stopping
+	shutdownTimeout = 5 * time.Second
+)
+
+// serveAddress of daemon, which listens on Unix socket within socketDir unless TCP address is configured
+func serveAddress(cfg config) (string, string) {
+	if cfg.Serve.Address != "" {
+		return "tcp", cfg.Serve.Address
+	}
+	return "unix", filepath.Join(socketDir(cfg), server.SocketFileName)
+}
+
+// daemon returns client of running daemon, or nil if it is not reachable or disabled with flag
+func daemon(ctx *cli.Context, cfg config) *server.Client {
+	if ctx.Bool("no-daemon") {
+		return nil
+	}
+	network, address := serveAddress(cfg)
+	if network == "unix" {
+		if _, err := os.Stat(address); err != nil {
+			return nil
+		}
+	}
+	client := server.NewClient(network, address)
+	if client.Ping(daemonPingTimeout) != nil {
+		return nil
+	}
+	return client
+}
+
+// checkLoopback address of TCP, as search API is served without authentication, which should not be reachable by others
+// Host is required to be loopback IP or localhost, where empty host of all interfaces is rejected as well
+func checkLoopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %v: %w", address, err)
+	}
+	if strings.EqualFold(host, "localhost") {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("%v is not a loopback address, use --allow-remote to serve unauthenticated API to others", address)
+}
+
+func serve(ctx *cli.Context, cfg config, indexer *fzd.Indexer) error {
+	network, address := serveAddress(cfg)
+	if ctx.IsSet("addr") {
+		network, address = "tcp", ctx.String("addr")
+	}
+	if network == "tcp" && !ctx.Bool("allow-remote") {
+		err := checkLoopback(address)
+		if err != nil {
+			return err
+		}
+	}
+	if server.NewClient(network, address).Ping(daemonPingTimeout) == nil {
+		return fmt.Errorf("daemon is already running on %v", address)
+	}
+
+	err := openOrIndex(ctx, indexer)
+	if err != nil {
+		return err
+	}
+	defer indexer.Close()
+
+	if network == "unix" {
+		err = os.MkdirAll(filepath.Dir(address), 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create %v: %w", filepath.Dir(address), err)
+		}
+		// socket is left behind if previous daemon is not stopped gracefully
+		err = os.Remove(address)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale socket %v: %w", address, err)
+		}
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %v: %w", address, err)
+	}
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Handler: server.NewHandler(indexer)}
+	errc := make(chan error, 2)
+	go func() {
+		errc <- srv.Serve(l)
+	}()
+
+	if ctx.Bool("watch") {
+		w, err := newWatcher(ctx, indexer)
+		if err != nil {
+			srv.Close()
+			return err
+		}
+		go func() {
+			errc <- w.Run(c)
+		}()
+	}
+
+	fmt.Printf("Serving on %v %v, press Ctrl-C to stop\n", network, address)
+	select {
+	case err = <-errc:
+		srv.Close()
+		return err
+	case <-c.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func watch(ctx *cli.Context, cfg config, indexer *fzd.Indexer) error {
+	if daemon(ctx, cfg) != nil {
+		// index is held open by daemon, which could keep it up to date instead
+		return fmt.Errorf("daemon is running, use \"fzd serve --watch\" to watch for changes")
+	}
+	err := openOrIndex(ctx, indexer)
+	if err != nil {
+		return err
+	}
+	defer indexer.Close()
+
+	w, err := newWatcher(ctx, indexer)
+	if err != nil {
+		return err
+	}
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Println("Watching for changes, press Ctrl-C to stop")
+	return w.Run(c)
+}
+
+// newWatcher catches up with changes by reindex, before watching for file system events
+func newWatcher(ctx *cli.Context, indexer *fzd.Indexer) (*fzd.Watcher, error) {
+	err := reindex(ctx, os.Stdout, indexer)
+	if err != nil {
+		return nil, err
+	}
+	return fzd.NewWatcher(indexer,
+		fzd.WithDebounce(ctx.Duration("debounce")),
+		fzd.WithRescanInterval(ctx.Duration("rescan")),
+		fzd.WithWatchErrorHandler(func(err error) {
+			logrus.Warn(err)
+		}),
+	)
+}
+
+// openOrIndex opens the index, or creates it if it does not exist yet and --yes is passed
+// It never prompts, as long running commands are likely to be run without terminal, i.e. as service
+func openOrIndex(ctx *cli.Context, indexer *fzd.Indexer) error {
+	err := indexer.Open()
+	if err == nil {
+		return nil
+	}
+	if !needsIndex(err) {
+		return err
+	}
+	if !ctx.Bool("yes") {
+		return noIndexError(err)
+	}
+	err = index(ctx, os.Stdout, indexer)
+	if err != nil {
+		return err
+	}
+	return indexer.Open()
+}
+
+func watchFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "debounce",
+			Value: fzd.DefaultDebounce,
+			Usage: "Duration for collecting file system events before applying them",
+		},
+		&cli.DurationFlag{
+			Name:  "rescan",
+			Value: fzd.DefaultRescanInterval,
+			Usage: "Interval of periodic reindex if file system watch limit is exceeded",
+		},
+	}
+}
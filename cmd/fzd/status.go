@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/horacehylee/fzd"
+	"github.com/urfave/cli/v2"
+)
+
+// status of current index through daemon if it is running, where index is not created if it does not exist
+func status(ctx *cli.Context, cfg config, indexer *fzd.Indexer) error {
+	if client := daemon(ctx, cfg); client != nil {
+		res, err := client.Status()
+		if err != nil {
+			return err
+		}
+		printStatus(res.IndexName, res.LastIndexed.Format("2006-01-02 15:04"), res.DocCount)
+		return nil
+	}
+
+	err := indexer.Open()
+	if needsIndex(err) {
+		return noIndexError(err)
+	}
+	if err != nil {
+		return err
+	}
+	defer indexer.Close()
+	name, err := indexer.IndexName()
+	if err != nil {
+		return err
+	}
+	t, err := indexer.LastIndexed()
+	if err != nil {
+		return err
+	}
+	count, err := indexer.DocCount()
+	if err != nil {
+		return err
+	}
+	printStatus(name, t.Format("2006-01-02 15:04"), count)
+	return nil
+}
+
+func printStatus(name string, lastIndexed string, count uint64) {
+	fmt.Printf("Index:         %v\n", name)
+	fmt.Printf("Last indexed:  %v\n", lastIndexed)
+	fmt.Printf("Files:         %v\n", count)
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/horacehylee/fzd/ignorer"
+	"github.com/spf13/viper"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// problem found in config, where line of config file is 0 if it is unknown, i.e. key is not specified
+type problem struct {
+	key     string
+	line    int
+	msg     string
+	warning bool
+}
+
+func (p problem) String() string {
+	s := fmt.Sprintf("%v: %v", p.key, p.msg)
+	if p.line != 0 {
+		s = fmt.Sprintf("line %v: %v", p.line, s)
+	}
+	if p.warning {
+		return "warning: " + s
+	}
+	return "error: " + s
+}
+
+// configLines of keys within config file, which are lowercased as keys are case insensitive
+// Keys of list elements are suffixed with index, i.e. locations[0].filters[1]
+type configLines map[string]int
+
+// find line of key, or its nearest parent if it is not specified
+func (l configLines) find(key string) int {
+	key = strings.ToLower(key)
+	for key != "" {
+		if line, ok := l[key]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(key, ".[")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	return 0
+}
+
+// removableRoots of mount points, where locations within them could be missing until drives are mounted
+var removableRoots = []string{"/media", "/mnt", "/run/media", "/Volumes"}
+
+// validateConfig for "fzd config validate", which exits with error status if any error is found
+func validateConfig() error {
+	cfg, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if printProblems(cfg.check(true)) {
+		return cli.Exit("", exitError)
+	}
+	return nil
+}
+
+// validate config read, where all errors are reported at once
+// Warnings and checks of file system are left for "fzd config validate", as they do not prevent using the config
+func (c *config) validate() error {
+	var errs []string
+	for _, p := range c.check(false) {
+		if !p.warning {
+			errs = append(errs, "  "+p.String())
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	name := "config"
+	if file := viper.ConfigFileUsed(); file != "" {
+		name = file
+	}
+	return fmt.Errorf("invalid %v, run \"fzd config validate\" for details:\n%v", name, strings.Join(errs, "\n"))
+}
+
+// check config for problems sorted by line, where paths of locations and base path are checked on file system only if specified
+func (c *config) check(paths bool) []problem {
+	lines, problems := checkConfigFile(viper.ConfigFileUsed())
+	problems = append(problems, c.checkIndex()...)
+	problems = append(problems, c.checkServe()...)
+	problems = append(problems, c.checkLocations()...)
+	if paths {
+		problems = append(problems, c.checkPaths()...)
+	}
+	for i := range problems {
+		if problems[i].line != 0 {
+			continue
+		}
+		// value overridden by environment variable is not within config file
+		if env := overridingEnv(problems[i].key); env != "" {
+			problems[i].key = fmt.Sprintf("%v (%v)", problems[i].key, env)
+			continue
+		}
+		problems[i].line = lines.find(problems[i].key)
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].line < problems[j].line
+	})
+	return problems
+}
+
+// checkConfigFile for unknown keys, and returns lines of keys within it
+// Unknown top level keys are allowed only if they define YAML anchors, i.e. default_ignores of example config
+// Only YAML and JSON, which is parsed as YAML, are checked, where config files of other formats are left without lines
+func checkConfigFile(file string) (configLines, []problem) {
+	lines := make(configLines)
+	if file == "" {
+		return lines, nil
+	}
+	if ext := configExt(file); supportedConfigExt(file) && ext != "yaml" && ext != "yml" && ext != "json" {
+		return lines, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return lines, []problem{{key: filepath.Base(file), msg: fmt.Sprintf("could not be read: %v", err)}}
+	}
+	var root yaml.Node
+	err = yaml.Unmarshal(b, &root)
+	if err != nil {
+		return lines, []problem{{key: filepath.Base(file), msg: fmt.Sprintf("could not be parsed: %v", err)}}
+	}
+	var problems []problem
+	walkConfigNode(&root, reflect.TypeOf(config{}), "", lines, &problems)
+	return lines, problems
+}
+
+func walkConfigNode(n *yaml.Node, t reflect.Type, key string, lines configLines, problems *[]problem) {
+	if n.Kind == yaml.DocumentNode {
+		for _, c := range n.Content {
+			walkConfigNode(c, t, key, lines, problems)
+		}
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case n.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			childKey := joinKey(key, k.Value)
+			lines[strings.ToLower(childKey)] = k.Line
+			field, ok := t.FieldByNameFunc(func(name string) bool {
+				return strings.EqualFold(name, k.Value)
+			})
+			if !ok {
+				if k.Value == "<<" || (key == "" && v.Anchor != "") {
+					continue
+				}
+				*problems = append(*problems, problem{key: childKey, line: k.Line, msg: "unknown key"})
+				continue
+			}
+			walkConfigNode(v, field.Type, childKey, lines, problems)
+		}
+	case n.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			childKey := joinKey(key, k.Value)
+			lines[strings.ToLower(childKey)] = k.Line
+			walkConfigNode(v, t.Elem(), childKey, lines, problems)
+		}
+	case n.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for i, v := range n.Content {
+			childKey := fmt.Sprintf("%v[%v]", key, i)
+			lines[strings.ToLower(childKey)] = v.Line
+			walkConfigNode(v, t.Elem(), childKey, lines, problems)
+		}
+	}
+}
+
+func joinKey(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func (c *config) checkIndex() []problem {
+	var problems []problem
+	if c.Index.BasePath == "" {
+		problems = append(problems, problem{key: "index.basePath", msg: "base path is required"})
+	}
+	if c.Index.Retention < 0 {
+		problems = append(problems, problem{key: "index.retention", msg: "retention cannot be negative"})
+	}
+	switch c.Index.AutoReindex {
+	case autoReindexNever, autoReindexIfMissing:
+	case autoReindexIfOlderThan:
+		if c.Index.MaxAge <= 0 {
+			problems = append(problems, problem{
+				key: "index.maxAge",
+				msg: fmt.Sprintf("max age should be positive for %v auto reindex", autoReindexIfOlderThan),
+			})
+		}
+	default:
+		problems = append(problems, problem{
+			key: "index.autoReindex",
+			msg: fmt.Sprintf("\"%v\" auto reindex is not supported, should be one of %v, %v or %v",
+				c.Index.AutoReindex, autoReindexNever, autoReindexIfMissing, autoReindexIfOlderThan),
+		})
+	}
+	return problems
+}
+
+// checkServe address to be loopback, which is warned as it could still be served with --allow-remote
+func (c *config) checkServe() []problem {
+	if c.Serve.Address == "" {
+		return nil
+	}
+	err := checkLoopback(c.Serve.Address)
+	if err != nil {
+		return []problem{{key: "serve.address", msg: err.Error(), warning: true}}
+	}
+	return nil
+}
+
+// checkLocations for unknown filters, invalid ignores and overlapping paths
+// Nested locations are allowed as paths are owned by the nearest one, but they are warned as they are likely to be unintended
+func (c *config) checkLocations() []problem {
+	if len(c.Locations) == 0 {
+		return []problem{{key: "locations", msg: "at least one location is required, otherwise nothing is indexed"}}
+	}
+	var problems []problem
+	for i, l := range c.Locations {
+		key := fmt.Sprintf("locations[%v]", i)
+		if l.Path == "" {
+			problems = append(problems, problem{key: key + ".path", msg: "path is required"})
+		}
+		for j, f := range l.Filters {
+			err := f.Validate()
+			if err != nil {
+				problems = append(problems, problem{key: fmt.Sprintf("%v.filters[%v]", key, j), msg: err.Error()})
+			}
+		}
+		for j, ignore := range l.Ignores {
+			_, err := ignorer.NewIgnorer(ignore)
+			if err != nil {
+				problems = append(problems, problem{key: fmt.Sprintf("%v.ignores[%v]", key, j), msg: err.Error()})
+			}
+		}
+		if l.Path == "" {
+			continue
+		}
+		for j, other := range c.Locations[:i] {
+			if other.Path == "" {
+				continue
+			}
+			otherKey := fmt.Sprintf("locations[%v]", j)
+			switch {
+			case l.Path == other.Path:
+				problems = append(problems, problem{
+					key: key + ".path",
+					msg: fmt.Sprintf("%v is already specified by %v", l.Path, otherKey),
+				})
+			case within(l.Path, other.Path), within(other.Path, l.Path):
+				problems = append(problems, problem{
+					key:     key + ".path",
+					msg:     fmt.Sprintf("%v overlaps with %v, where paths are owned by the nearest location", l.Path, otherKey),
+					warning: true,
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// checkPaths of locations to be existing directories, and base path to be writable
+// Locations that do not exist are errors, unless they are optional or within removable roots, as they could be mounted later
+func (c *config) checkPaths() []problem {
+	var problems []problem
+	for i, l := range c.Locations {
+		if l.Path == "" {
+			continue
+		}
+		key := fmt.Sprintf("locations[%v].path", i)
+		info, err := os.Stat(l.Path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			if l.Optional || removable(l.Path) {
+				problems = append(problems, problem{key: key, msg: fmt.Sprintf("%v does not exist", l.Path), warning: true})
+				continue
+			}
+			problems = append(problems, problem{
+				key: key,
+				msg: fmt.Sprintf("%v does not exist, set optional to true if it could be mounted later", l.Path),
+			})
+		case err != nil:
+			problems = append(problems, problem{key: key, msg: err.Error()})
+		case !info.IsDir():
+			problems = append(problems, problem{key: key, msg: fmt.Sprintf("%v is not a directory", l.Path)})
+		}
+	}
+	if c.Index.BasePath != "" {
+		err := checkWritable(c.Index.BasePath)
+		if err != nil {
+			problems = append(problems, problem{key: "index.basePath", msg: err.Error()})
+		}
+	}
+	return problems
+}
+
+// checkWritable by creating a temporary file within directory
+// Directory is created on indexing if it does not exist, so its nearest existing parent is checked instead
+func checkWritable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%v is not a directory", dir)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".fzd-*")
+	if err != nil {
+		return fmt.Errorf("%v is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// removable checks if path is within one of removableRoots, or on volume that does not exist, i.e. unplugged drive on Windows
+func removable(path string) bool {
+	for _, root := range removableRoots {
+		if within(path, root) {
+			return true
+		}
+	}
+	if volume := filepath.VolumeName(path); volume != "" {
+		_, err := os.Stat(volume + string(filepath.Separator))
+		return errors.Is(err, os.ErrNotExist)
+	}
+	return false
+}
+
+// within checks if path is within dir, excluding dir itself
+func within(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// printProblems found in config, and returns if there is any error
+func printProblems(problems []problem) bool {
+	var errs, warnings int
+	for _, p := range problems {
+		fmt.Println(p)
+		if p.warning {
+			warnings++
+		} else {
+			errs++
+		}
+	}
+	switch {
+	case errs != 0:
+		fmt.Printf("Config is invalid with %v errors and %v warnings\n", errs, warnings)
+	case warnings != 0:
+		fmt.Printf("Config is valid with %v warnings\n", warnings)
+	default:
+		fmt.Println("Config is valid")
+	}
+	return errs != 0
+}
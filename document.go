@@ -0,0 +1,68 @@
+package fzd
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/horacehylee/fzd/walker"
+)
+
+// Document of file entry stored within the index, which is identified by its path
+type Document struct {
+	// Path is the absolute path of file entry
+	Path string `json:"path"`
+
+	// Name is the base name of file entry
+	Name string `json:"name"`
+
+	// Dir is the parent directory of file entry
+	Dir string `json:"dir"`
+
+	// Ext is the lowercased extension of file entry without leading dot
+	Ext string `json:"ext"`
+
+	// Size is the length in bytes for regular files
+	Size int64 `json:"size"`
+
+	// ModTime is the modification time of file entry
+	ModTime time.Time `json:"modTime"`
+
+	// IsDir is true if file entry is a directory
+	IsDir bool `json:"isDir"`
+
+	// Location is the path of location that file entry is traversed from
+	Location string `json:"location"`
+}
+
+// Field names of Document within the index
+const (
+	FieldPath     = "path"
+	FieldName     = "name"
+	FieldDir      = "dir"
+	FieldExt      = "ext"
+	FieldSize     = "size"
+	FieldModTime  = "modTime"
+	FieldIsDir    = "isDir"
+	FieldLocation = "location"
+)
+
+// FieldSegments is field of directory segments analyzed from Dir, which is only indexed for searching but not stored
+const FieldSegments = "segments"
+
+func newDocument(location string, path string, info walker.FileInfo) Document {
+	var ext string
+	if !info.IsDir() {
+		ext = strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	}
+	return Document{
+		Path:     path,
+		Name:     filepath.Base(path),
+		Dir:      filepath.Dir(path),
+		Ext:      ext,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		IsDir:    info.IsDir(),
+		Location: location,
+	}
+}
@@ -0,0 +1,44 @@
+package fzd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDocumentForFile(t *testing.T) {
+	location := filepath.Clean("/level0")
+	path := filepath.Clean("/level0/level1/Level1.TXT")
+	modTime := time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)
+	fileInfo := &mockFileinfo{
+		mode:    fileMode,
+		name:    filepath.Base(path),
+		size:    7,
+		modTime: modTime,
+	}
+
+	doc := newDocument(location, path, fileInfo)
+	assert.Equal(t, Document{
+		Path:     path,
+		Name:     "Level1.TXT",
+		Dir:      filepath.Clean("/level0/level1"),
+		Ext:      "txt",
+		Size:     7,
+		ModTime:  modTime,
+		IsDir:    false,
+		Location: location,
+	}, doc)
+}
+
+func TestNewDocumentForDir(t *testing.T) {
+	location := filepath.Clean("/level0")
+	path := filepath.Clean("/level0/level1.d")
+	fileInfo := newMockFileInfo(filepath.Base(path), fileMode, true)
+
+	doc := newDocument(location, path, fileInfo)
+	assert.Equal(t, "level1.d", doc.Name)
+	assert.Equal(t, "", doc.Ext, "directory should not have extension")
+	assert.True(t, doc.IsDir)
+}
@@ -22,6 +22,15 @@ const (
 	NotDir Filter = "not_dir"
 )
 
+// Validate checks if filter is supported
+func (f Filter) Validate() error {
+	switch f {
+	case Top, Dir, NotDir:
+		return nil
+	}
+	return fmt.Errorf("\"%v\" filter is not supported", f)
+}
+
 func withTopFilter(root string) walker.WalkFunc {
 	cleanedRoot := filepath.Clean(root)
 	return func(path string, info walker.FileInfo, err error) error {
@@ -86,6 +95,10 @@ func withIgnoreFilter(ignores ...interface{}) (walker.WalkFunc, error) {
 func newFiltersWalkFunc(root string, option LocationOption) (walker.WalkFunc, error) {
 	var walkFuncs []walker.WalkFunc
 	for _, f := range option.Filters {
+		err := f.Validate()
+		if err != nil {
+			return nil, err
+		}
 		switch f {
 		case Top:
 			walkFuncs = append(walkFuncs, withTopFilter(root))
@@ -93,8 +106,6 @@ func newFiltersWalkFunc(root string, option LocationOption) (walker.WalkFunc, er
 			walkFuncs = append(walkFuncs, withDirFilter())
 		case NotDir:
 			walkFuncs = append(walkFuncs, withNotDirFilter(root))
-		default:
-			return nil, fmt.Errorf("\"%v\" filter is not supported", f)
 		}
 	}
 
@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/horacehylee/fzd/ignorer"
 	"github.com/horacehylee/fzd/walker"
@@ -12,9 +13,11 @@ import (
 )
 
 type mockFileinfo struct {
-	mode  fs.FileMode
-	name  string
-	isDir bool
+	mode    fs.FileMode
+	name    string
+	isDir   bool
+	size    int64
+	modTime time.Time
 }
 
 func (m *mockFileinfo) Mode() fs.FileMode {
@@ -29,6 +32,14 @@ func (m *mockFileinfo) IsDir() bool {
 	return m.isDir
 }
 
+func (m *mockFileinfo) Size() int64 {
+	return m.size
+}
+
+func (m *mockFileinfo) ModTime() time.Time {
+	return m.modTime
+}
+
 func newMockFileInfo(name string, mode fs.FileMode, isDir bool) *mockFileinfo {
 	return &mockFileinfo{
 		mode:  mode,
@@ -250,3 +261,10 @@ func TestWithIgnoreFilterReturnsErrorIfNotStringRelated(t *testing.T) {
 	_, err := withIgnoreFilter(123)
 	assert.ErrorIs(t, err, ignorer.ErrTypeNotSupported)
 }
+
+func TestFilterValidate(t *testing.T) {
+	for _, f := range []Filter{Top, Dir, NotDir} {
+		assert.NoError(t, f.Validate())
+	}
+	assert.EqualError(t, Filter("tpo").Validate(), "\"tpo\" filter is not supported")
+}
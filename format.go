@@ -0,0 +1,260 @@
+package fzd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+// Format of search hits output
+type Format string
+
+const (
+	// Plain formats hits as paths delimited by newline
+	Plain Format = "plain"
+
+	// JSON formats hits as a JSON array
+	JSON Format = "json"
+
+	// JSONL formats hits as JSON objects delimited by newline
+	JSONL Format = "jsonl"
+
+	// Null formats hits as paths delimited by NUL character, i.e. for xargs -0
+	Null Format = "null"
+)
+
+// Hit of search result, with Document fields that are loaded for the hit
+type Hit struct {
+	Document
+
+	// Score of the hit for the search term
+	Score float64 `json:"score"`
+
+	// Fragments of fields with matched terms highlighted, if highlight is requested
+	Fragments map[string][]string `json:"fragments,omitempty"`
+
+	// Matches are sorted ranges of path matched by the term without overlaps, if highlight is requested
+	Matches []MatchRange `json:"matches,omitempty"`
+}
+
+// MatchRange is range of bytes within path matched by the term, from start inclusive to end exclusive
+type MatchRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Highlight path of hit, where each of matched ranges is wrapped with start and end markers, i.e. ANSI escape codes
+func (h Hit) Highlight(start string, end string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range h.Matches {
+		b.WriteString(h.Path[last:m.Start])
+		b.WriteString(start)
+		b.WriteString(h.Path[m.Start:m.End])
+		b.WriteString(end)
+		last = m.End
+	}
+	b.WriteString(h.Path[last:])
+	return b.String()
+}
+
+// NewHits converts hits of search result, where Document fields are filled only if they are loaded
+func NewHits(res *bleve.SearchResult) []Hit {
+	hits := make([]Hit, 0, len(res.Hits))
+	for _, h := range res.Hits {
+		hits = append(hits, newHit(h))
+	}
+	return hits
+}
+
+func newHit(h *search.DocumentMatch) Hit {
+	hit := Hit{
+		Document: Document{
+			Path: h.ID,
+		},
+		Score:     h.Score,
+		Fragments: h.Fragments,
+		Matches:   newMatches(h),
+	}
+	if v, ok := h.Fields[FieldName].(string); ok {
+		hit.Name = v
+	}
+	if v, ok := h.Fields[FieldDir].(string); ok {
+		hit.Dir = v
+	}
+	if v, ok := h.Fields[FieldExt].(string); ok {
+		hit.Ext = v
+	}
+	if v, ok := h.Fields[FieldSize].(float64); ok {
+		hit.Size = int64(v)
+	}
+	if v, ok := h.Fields[FieldModTime].(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err == nil {
+			hit.ModTime = t
+		}
+	}
+	if v, ok := h.Fields[FieldIsDir].(bool); ok {
+		hit.IsDir = v
+	}
+	if v, ok := h.Fields[FieldLocation].(string); ok {
+		hit.Location = v
+	}
+	return hit
+}
+
+// newMatches from locations of matched terms within fields, which are offset into ranges of path
+// Name is the base of path, while directory segments and composite field are prefix of path, so they could be mapped back to path
+func newMatches(h *search.DocumentMatch) []MatchRange {
+	var matches []MatchRange
+	for field, terms := range h.Locations {
+		var offset int
+		switch field {
+		case FieldPath, FieldSegments, "_all":
+		case FieldName:
+			offset = len(h.ID) - len(filepath.Base(h.ID))
+		default:
+			continue
+		}
+		for _, locations := range terms {
+			for _, l := range locations {
+				start, end := offset+int(l.Start), offset+int(l.End)
+				if start < 0 || start >= end || end > len(h.ID) {
+					continue
+				}
+				matches = append(matches, MatchRange{Start: start, End: end})
+			}
+		}
+	}
+	return mergeMatches(matches)
+}
+
+// mergeMatches sorts ranges and merges overlapping or adjacent ones
+func mergeMatches(matches []MatchRange) []MatchRange {
+	if len(matches) == 0 {
+		return nil
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+	merged := matches[:1]
+	for _, m := range matches[1:] {
+		last := &merged[len(merged)-1]
+		if m.Start > last.End {
+			merged = append(merged, m)
+			continue
+		}
+		if m.End > last.End {
+			last.End = m.End
+		}
+	}
+	return merged
+}
+
+// Formatter writes search hits to writer
+type Formatter interface {
+	Format(w io.Writer, hits []Hit) error
+}
+
+// FormatterFunc is an adapter to allow the use of ordinary functions as Formatter
+type FormatterFunc func(w io.Writer, hits []Hit) error
+
+// Format calls f(w, hits)
+func (f FormatterFunc) Format(w io.Writer, hits []Hit) error {
+	return f(w, hits)
+}
+
+// NewFormatter for specified format, it could be one of the Format or Go template executed for each hit, i.e. "{{.Path}} {{.Score}}"
+func NewFormatter(format string) (Formatter, error) {
+	switch Format(format) {
+	case Plain:
+		return newDelimitedFormatter("\n"), nil
+	case Null:
+		return newDelimitedFormatter("\x00"), nil
+	case JSON:
+		return FormatterFunc(formatJSON), nil
+	case JSONL:
+		return FormatterFunc(formatJSONL), nil
+	}
+	if !strings.Contains(format, "{{") {
+		return nil, fmt.Errorf("\"%v\" format is not supported", format)
+	}
+	return newTemplateFormatter(format)
+}
+
+// FormatNeedsFields checks if Document fields and fragments are required to be loaded for specified format
+func FormatNeedsFields(format string) bool {
+	f := Format(format)
+	return f != Plain && f != Null
+}
+
+// NewHighlightFormatter formats paths of hits delimited by newline, where matched ranges are wrapped with start and end markers
+// Matches of hits are only available if highlight is requested
+func NewHighlightFormatter(start string, end string) Formatter {
+	return FormatterFunc(func(w io.Writer, hits []Hit) error {
+		for _, h := range hits {
+			_, err := fmt.Fprintln(w, h.Highlight(start, end))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func newDelimitedFormatter(delimiter string) Formatter {
+	return FormatterFunc(func(w io.Writer, hits []Hit) error {
+		for _, h := range hits {
+			_, err := fmt.Fprintf(w, "%v%v", h.Path, delimiter)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func formatJSON(w io.Writer, hits []Hit) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(hits)
+}
+
+func formatJSONL(w io.Writer, hits []Hit) error {
+	enc := json.NewEncoder(w)
+	for _, h := range hits {
+		err := enc.Encode(h)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTemplateFormatter(format string) (Formatter, error) {
+	tmpl, err := template.New("hit").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse format template: %w", err)
+	}
+	return FormatterFunc(func(w io.Writer, hits []Hit) error {
+		for _, h := range hits {
+			err := tmpl.Execute(w, h)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(w)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}), nil
+}
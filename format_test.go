@@ -0,0 +1,180 @@
+package fzd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/stretchr/testify/assert"
+)
+
+var formatTestHits = []Hit{
+	{
+		Document: Document{
+			Path:    "/level0/level0.txt",
+			Name:    "level0.txt",
+			Ext:     "txt",
+			ModTime: time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		Score: 1.5,
+	},
+	{
+		Document: Document{
+			Path:  "/level0/level1",
+			Name:  "level1",
+			IsDir: true,
+		},
+		Score:     0.5,
+		Fragments: map[string][]string{FieldPath: {"/level0/<mark>level1</mark>"}},
+	},
+}
+
+func TestPlainFormatter(t *testing.T) {
+	f, err := NewFormatter("plain")
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	err = f.Format(&b, formatTestHits)
+	assert.NoError(t, err)
+	assert.Equal(t, "/level0/level0.txt\n/level0/level1\n", b.String())
+}
+
+func TestNullFormatter(t *testing.T) {
+	f, err := NewFormatter("null")
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	err = f.Format(&b, formatTestHits)
+	assert.NoError(t, err)
+	assert.Equal(t, "/level0/level0.txt\x00/level0/level1\x00", b.String())
+}
+
+func TestJSONFormatter(t *testing.T) {
+	f, err := NewFormatter("json")
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	err = f.Format(&b, formatTestHits)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"path": "/level0/level0.txt", "name": "level0.txt", "dir": "", "ext": "txt", "size": 0,
+		 "modTime": "2022-01-02T15:04:05Z", "isDir": false, "location": "", "score": 1.5},
+		{"path": "/level0/level1", "name": "level1", "dir": "", "ext": "", "size": 0,
+		 "modTime": "0001-01-01T00:00:00Z", "isDir": true, "location": "", "score": 0.5,
+		 "fragments": {"path": ["/level0/<mark>level1</mark>"]}}
+	]`, b.String())
+}
+
+func TestJSONLFormatter(t *testing.T) {
+	f, err := NewFormatter("jsonl")
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	err = f.Format(&b, formatTestHits)
+	assert.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n"))
+	assert.Equal(t, 2, len(lines))
+	assert.Contains(t, string(lines[0]), `"path":"/level0/level0.txt"`)
+	assert.Contains(t, string(lines[1]), `"path":"/level0/level1"`)
+}
+
+func TestTemplateFormatter(t *testing.T) {
+	f, err := NewFormatter("{{.Name}} {{.Score}} {{.IsDir}}")
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	err = f.Format(&b, formatTestHits)
+	assert.NoError(t, err)
+	assert.Equal(t, "level0.txt 1.5 false\nlevel1 0.5 true\n", b.String())
+}
+
+func TestNewFormatterReturnsErrorIfNotSupported(t *testing.T) {
+	_, err := NewFormatter("xml")
+	assert.EqualError(t, err, "\"xml\" format is not supported")
+
+	_, err = NewFormatter("{{.Path")
+	assert.Error(t, err)
+}
+
+func TestHighlightFormatter(t *testing.T) {
+	hits := []Hit{
+		{Document: Document{Path: "/level0/level1"}, Matches: []MatchRange{{Start: 1, End: 6}, {Start: 8, End: 13}}},
+		{Document: Document{Path: "/level0/level0.txt"}},
+	}
+	f := NewHighlightFormatter("[", "]")
+
+	var b bytes.Buffer
+	err := f.Format(&b, hits)
+	assert.NoError(t, err)
+	assert.Equal(t, "/[level]0/[level]1\n/level0/level0.txt\n", b.String())
+}
+
+func TestMergeMatches(t *testing.T) {
+	matches := mergeMatches([]MatchRange{{Start: 8, End: 12}, {Start: 1, End: 4}, {Start: 3, End: 6}, {Start: 12, End: 14}})
+	assert.Equal(t, []MatchRange{{Start: 1, End: 6}, {Start: 8, End: 14}}, matches)
+	assert.Nil(t, mergeMatches(nil))
+}
+
+func TestFormatNeedsFields(t *testing.T) {
+	assert.False(t, FormatNeedsFields("plain"))
+	assert.False(t, FormatNeedsFields("null"))
+	assert.True(t, FormatNeedsFields("json"))
+	assert.True(t, FormatNeedsFields("jsonl"))
+	assert.True(t, FormatNeedsFields("{{.Path}}"))
+}
+
+func TestNewHits(t *testing.T) {
+	res := &bleve.SearchResult{
+		Hits: search.DocumentMatchCollection{
+			{
+				ID:    "/level0/level0.txt",
+				Score: 1.5,
+				Fields: map[string]interface{}{
+					FieldName:     "level0.txt",
+					FieldDir:      "/level0",
+					FieldExt:      "txt",
+					FieldSize:     float64(7),
+					FieldModTime:  "2022-01-02T15:04:05Z",
+					FieldIsDir:    false,
+					FieldLocation: "/level0",
+				},
+				Fragments: map[string][]string{FieldPath: {"/level0/<mark>level0</mark>.txt"}},
+				Locations: search.FieldTermLocationMap{
+					FieldPath:     {"level0": {{Start: 8, End: 14}}},
+					FieldName:     {"level0": {{Start: 0, End: 6}}, "txt": {{Start: 7, End: 10}}},
+					FieldSegments: {"level0": {{Start: 1, End: 7}}},
+				},
+			},
+			{
+				ID:    "/level0/level1",
+				Score: 0.5,
+			},
+		},
+	}
+
+	hits := NewHits(res)
+	assert.Equal(t, []Hit{
+		{
+			Document: Document{
+				Path:     "/level0/level0.txt",
+				Name:     "level0.txt",
+				Dir:      "/level0",
+				Ext:      "txt",
+				Size:     7,
+				ModTime:  time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+				IsDir:    false,
+				Location: "/level0",
+			},
+			Score:     1.5,
+			Fragments: map[string][]string{FieldPath: {"/level0/<mark>level0</mark>.txt"}},
+			Matches:   []MatchRange{{Start: 1, End: 7}, {Start: 8, End: 14}, {Start: 15, End: 18}},
+		},
+		{
+			Document: Document{Path: "/level0/level1"},
+			Score:    0.5,
+		},
+	}, hits)
+}
@@ -1,10 +1,12 @@
 package fzd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
 
@@ -18,15 +20,48 @@ var (
 
 	// Error where HEAD file does not exists, it should only occur when opened without previously indexed
 	ErrIndexHeadDoesNotExist = fmt.Errorf("cannot open index, %v file does not exist", HeadFileName)
+
+	// Error where HEAD file could not be parsed or specifies invalid index, i.e. truncated or edited by hand
+	ErrIndexHeadCorrupted = fmt.Errorf("cannot open index, %v file is corrupted", HeadFileName)
+
+	// Error where index is created with different mapping version, which should be indexed again from scratch
+	ErrIndexIncompatible = errors.New("index is incompatible")
+
+	// Error where index manifest does not exist, it should only occur for index created without tracking of modification times
+	ErrIndexManifestDoesNotExist = errors.New("index manifest does not exist")
+
+	// Error where index is swapped while incremental reindex is in progress
+	ErrIndexSwapped = errors.New("index is swapped during reindex")
+
+	// Error where location to be indexed is not configured with WithLocation
+	ErrLocationNotConfigured = errors.New("location is not configured")
 )
 
+// Version of fzd, which is recorded in HEAD file and overridden on release with -ldflags "-X github.com/horacehylee/fzd.Version=..."
+var Version = "dev"
+
+// DefaultConcurrency is default number of workers for walking locations
+var DefaultConcurrency = runtime.NumCPU()
+
 // Indexer manages file path indexes, which provides atomic reindex swapping
 type Indexer struct {
-	locations map[string]LocationOption
-	basePath  string
-	index     *singleIndexAlias
-	mutex     sync.RWMutex
-	open      bool
+	locations   map[string]LocationOption
+	basePath    string
+	concurrency int
+	failFast    bool
+	lockTimeout time.Duration
+	retention   int
+	analyzer    AnalyzerOptions
+	lock        *baseLock
+	genLocks    *generationLocks
+	index       *indexAlias
+	head        Head
+	history     *history
+	mutex       sync.RWMutex
+	open        bool
+
+	// batchMutex serializes incremental updates to opened index, as manifests are read and written back
+	batchMutex sync.Mutex
 }
 
 // LocationOption of options on traversing the specified directory location tree
@@ -46,7 +81,29 @@ type IndexerOption func(*Indexer)
 // WithLocation allows specificing directory location and options for traversing it
 func WithLocation(path string, option LocationOption) IndexerOption {
 	return func(i *Indexer) {
-		i.locations[path] = option
+		i.locations[filepath.Clean(path)] = option
+	}
+}
+
+// WithConcurrency sets number of workers for walking directories, which are shared across all locations
+// Concurrency of 1 walks locations one after another sequentially
+func WithConcurrency(concurrency int) IndexerOption {
+	return func(i *Indexer) {
+		i.concurrency = concurrency
+	}
+}
+
+// WithFailFast stops indexing on the first error, instead of skipping erroneous paths and reporting them
+func WithFailFast(failFast bool) IndexerOption {
+	return func(i *Indexer) {
+		i.failFast = failFast
+	}
+}
+
+// WithLockTimeout sets duration for waiting the lock of base path held by other processes, before ErrIndexLocked is returned
+func WithLockTimeout(timeout time.Duration) IndexerOption {
+	return func(i *Indexer) {
+		i.lockTimeout = timeout
 	}
 }
 
@@ -56,12 +113,22 @@ func NewIndexer(basePath string, options ...IndexerOption) (*Indexer, error) {
 		return nil, fmt.Errorf("base path cannot be empty")
 	}
 	i := &Indexer{
-		locations: make(map[string]LocationOption),
-		basePath:  basePath,
+		locations:   make(map[string]LocationOption),
+		basePath:    basePath,
+		concurrency: DefaultConcurrency,
+		lockTimeout: DefaultLockTimeout,
+		retention:   DefaultRetention,
 	}
 	for _, option := range options {
 		option(i)
 	}
+	err := i.analyzer.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid analyzer: %w", err)
+	}
+	i.lock = newBaseLock(basePath, i.lockTimeout)
+	i.genLocks = newGenerationLocks(basePath, i.lockTimeout)
+	i.history = newHistory(basePath, i.lock)
 	return i, nil
 }
 
@@ -72,11 +139,34 @@ func (i *Indexer) Open() error {
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
 
-	name, err := readHead(i.basePath)
+	// shared lock prevents generations specified by HEAD file from being removed before they are locked once opened
+	err := i.lock.acquire()
+	if err != nil {
+		return err
+	}
+	defer i.lock.release()
+
+	head, err := readHead(i.basePath)
+	if err != nil {
+		return err
+	}
+	if head.MappingVersion == 0 {
+		// HEAD file of older versions only specifies name, while generation of the index may record its sub-indexes
+		gen, err := readGeneration(i.basePath, head.Name)
+		if err == nil && gen.MappingVersion != 0 {
+			head = gen
+		}
+	}
+	err = head.compatible(i.analyzer)
+	if err != nil {
+		return err
+	}
+	err = i.openAndSwap(head)
 	if err != nil {
 		return err
 	}
-	return i.openAndSwap(name)
+	i.head = head
+	return nil
 }
 
 // OpenAndSwap writes specified index to HEAD file, open and swap it with current index
@@ -86,92 +176,551 @@ func (i *Indexer) OpenAndSwap(name string) error {
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
 
-	if i.index == nil || i.index.name() != name {
+	if i.index != nil && i.index.name() == name {
 		// if same index is passed, no need to update HEAD and swap index
-		err := writeHead(i.basePath, name)
+		return nil
+	}
+	// exclusive lock serializes writers of HEAD file, such that HEAD file and the generation opened are consistent
+	return i.lock.exclusive(func() error {
+		head, err := readGeneration(i.basePath, name)
+		if err != nil {
+			return err
+		}
+		if head.MappingVersion == 0 {
+			// single index of older versions, which is built before generations are recorded
+			head = newGeneration(name, i.locations, nil, AnalyzerOptions{}, 0)
+		}
+		err = head.compatible(i.analyzer)
 		if err != nil {
 			return err
 		}
-		err = i.openAndSwap(name)
+
+		// index is opened before HEAD is written, such that HEAD never specifies index which could not be opened
+		err = i.openAndSwap(head)
+		if err != nil {
+			return err
+		}
+		head.DocCount, err = i.index.docCount()
 		if err != nil {
 			return err
 		}
+		err = writeHead(i.basePath, head)
+		if err != nil {
+			return err
+		}
+		if head.Locations != nil {
+			// doc count recorded while building excludes sub-indexes carried over from other generations
+			err = writeGeneration(i.basePath, head)
+			if err != nil {
+				return err
+			}
+		}
+		i.head = head
+		return nil
+	})
+}
+
+// Head returns manifest of HEAD file for currently opened index
+// If index not opened, ErrIndexNotOpened is returned
+func (i *Indexer) Head() (Head, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	if i.index == nil || !i.open {
+		return Head{}, ErrIndexNotOpened
 	}
-	return nil
+	return i.head, nil
 }
 
-// Caller of openAndSwap should acquire Write lock of mutex to be concurrent-safe
-func (i *Indexer) openAndSwap(name string) error {
-	if i.index != nil && i.index.name() == name {
+// Caller of openAndSwap should acquire Write lock of mutex and lock of base path to be concurrent-safe
+// Sub-indexes already opened are reused, such that only those of changed locations are opened and closed
+// Generations of sub-indexes are locked until they are swapped out or closed, such that they are never removed by others
+func (i *Indexer) openAndSwap(head Head) error {
+	if i.index != nil && i.index.name() == head.Name {
 		// do nothing if index with same name is loaded
 		return nil
 	}
+	if i.index == nil {
+		i.index = newIndexAlias()
+	}
 
-	path := filepath.Join(i.basePath, name)
-	index, err := bleve.Open(path)
-	if err != nil {
-		return fmt.Errorf("could not open %v specified by %v: %w", path, HeadFileName, err)
+	names := head.Locations
+	if names == nil {
+		// single index of older versions for all locations
+		names = map[string]string{"": ""}
+	}
+	indexes := make(map[string]bleve.Index, len(names))
+	var opened []bleve.Index
+	for location, generation := range names {
+		name := head.Name
+		if location != "" {
+			name = locationIndexName(generation, location)
+		}
+		if index, ok := i.index.find(name); ok {
+			indexes[location] = index
+			continue
+		}
+		path := filepath.Join(i.basePath, name)
+		index, err := bleve.Open(path)
+		if err != nil {
+			for _, index := range opened {
+				index.Close()
+			}
+			return fmt.Errorf("could not open %v specified by %v: %w", path, HeadFileName, err)
+		}
+		index.SetName(name)
+		indexes[location] = index
+		opened = append(opened, index)
 	}
-	index.SetName(name)
 
-	if i.index == nil {
-		i.index = newSingleIndexAlias(index)
-	} else {
-		prev := i.index.get()
-		i.index.swap(index)
+	err := i.genLocks.acquire(generationsOf(indexes)...)
+	if err != nil {
+		for _, index := range opened {
+			index.Close()
+		}
+		return err
+	}
+	prevGenerations := i.index.generations()
 
-		// close previous index
-		err = prev.Close()
-		if err != nil {
-			return fmt.Errorf("failed to close previous index: %w", err)
+	// close previous sub-indexes which are not used anymore
+	for _, prev := range i.index.swap(head.Name, indexes) {
+		if closeErr := prev.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close previous index: %w", closeErr)
 		}
 	}
+	if releaseErr := i.genLocks.release(prevGenerations...); releaseErr != nil && err == nil {
+		err = releaseErr
+	}
 	i.open = true
-	return nil
+	return err
 }
 
 // Index will create new index from scratch for all file entries
 // Such that files generations and deletions are not required to be tracked
 // To use newly created index, use OpenAndSwap with returned index name
 func (i *Indexer) Index() (string, error) {
-	// no mutex locking is needed, as it will create a new index
-	name := newIndexName()
+	name, _, err := i.IndexWithReport()
+	return name, err
+}
+
+// IndexWithReport creates new index from scratch as Index does, along with report of paths walked and skipped
+// Paths which could not be walked are skipped and reported, unless fail fast where the first error is returned
+func (i *Indexer) IndexWithReport() (string, *IndexReport, error) {
+	return i.IndexContext(context.Background(), IndexOptions{})
+}
 
+// IndexContext creates new index from scratch as IndexWithReport does, where progress is reported with options
+// If context is done before the index is built, the partially built index is removed and the context error is returned
+func (i *Indexer) IndexContext(ctx context.Context, opts IndexOptions) (name string, report *IndexReport, err error) {
+	// no mutex locking is needed, as it will create a new index
+	// while locks of generations prevent the new one and those carried over from being removed by others while being built
+	var generations map[string]string
+	var locations map[string]LocationOption
+	name, generations, locations, err = i.createGeneration(opts.Locations)
+	if err != nil {
+		return "", nil, err
+	}
 	newIndexPath := filepath.Join(i.basePath, name)
-	mapping, err := newIndexMapping()
+	defer func() {
+		if err != nil {
+			os.RemoveAll(newIndexPath)
+		}
+	}()
+	// locks are released before the new generation is removed on error, as lock file could not be removed while opened on Windows
+	defer i.genLocks.release(generationNames(generations)...)
+
+	mapping, err := newIndexMapping(i.analyzer)
+	if err != nil {
+		return "", nil, err
+	}
+	// segments are built within temp directory, which should be removed even if indexing is aborted
+	buildPath, err := os.MkdirTemp("", "fzd-build")
 	if err != nil {
-		return "", err
+		return "", nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(buildPath)
+
+	// each location is built into its own sub-index, such that it could be rebuilt separately
+	builders := make(map[string]*builder, len(locations))
+	manifests := make(map[string]manifest, len(locations))
+	for location := range locations {
+		config := map[string]interface{}{
+			"buildPathPrefix": buildPath,
+		}
+		b, err := bleve.NewBuilder(filepath.Join(i.basePath, locationIndexName(name, location)), mapping, config)
+		if err != nil {
+			return "", nil, err
+		}
+		builders[location] = &builder{Builder: b}
+		manifests[location] = make(manifest)
 	}
-	config := make(map[string]interface{})
-	builder, err := bleve.NewBuilder(newIndexPath, mapping, config)
+
+	r := newReporter(i.failFast)
+	t := newTracker()
+	stop := t.report(opts)
+	var mutex sync.Mutex
+	err = i.walkLocations(ctx, locations, r, t, func(path string, option LocationOption) (walker.WalkFunc, error) {
+		filtersWalkFunc, err := newFiltersWalkFunc(path, option)
+		if err != nil {
+			return nil, err
+		}
+
+		// combine index walkFunc last, builder is concurrent-safe while manifest is not
+		m := manifests[path]
+		return walker.Chain(filtersWalkFunc, walker.Synchronized(&mutex, newManifestWalkFunc(m)), newIndexWalkFunc(builders[path], path)), nil
+	})
+	stop()
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+	// merging segments could not be cancelled, so context is checked before it
+	err = ctx.Err()
+	if err != nil {
+		return "", nil, err
+	}
+
+	for location, b := range builders {
+		path := filepath.Join(i.basePath, locationIndexName(name, location))
+		err = b.close(path, mapping)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to execute index batch of %v: %w", location, err)
+		}
+		err = writeIndexManifests(path, map[string]manifest{location: manifests[location]})
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	report = r.result()
+	err = writeGeneration(i.basePath, newGeneration(name, i.locations, generations, i.analyzer, uint64(report.Paths())))
+	if err != nil {
+		return "", nil, err
+	}
+	return name, report, nil
+}
+
+// createGeneration creates directory of new generation, and returns generations of locations along with locations to be built
+// Generations are locked with shared lock of base path held, such that those carried over are not removed before locked
+func (i *Indexer) createGeneration(only []string) (string, map[string]string, map[string]LocationOption, error) {
+	err := i.lock.acquire()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	defer i.lock.release()
+
+	generations, locations, err := i.locationsToBuild(only)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	name := newIndexName()
+	for location := range locations {
+		generations[location] = name
+	}
+	path := filepath.Join(i.basePath, name)
+	err = os.MkdirAll(path, 0700)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+	err = i.genLocks.acquire(generationNames(generations)...)
+	if err != nil {
+		os.RemoveAll(path)
+		return "", nil, nil, err
+	}
+	return name, generations, locations, nil
+}
+
+// generationNames of locations without duplicates
+func generationNames(generations map[string]string) []string {
+	var names []string
+	for _, name := range generations {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// locationsToBuild returns generations of locations carried over from HEAD file, and locations to be built
+// All locations are built if not specified, along with locations which are not indexed by HEAD file yet
+func (i *Indexer) locationsToBuild(only []string) (map[string]string, map[string]LocationOption, error) {
+	generations := make(map[string]string)
+	if len(only) == 0 {
+		return generations, i.locations, nil
+	}
+
+	locations := make(map[string]LocationOption)
+	for _, location := range only {
+		option, ok := i.locations[filepath.Clean(location)]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %v", ErrLocationNotConfigured, location)
+		}
+		locations[filepath.Clean(location)] = option
+	}
+	head, err := readHead(i.basePath)
+	if err != nil && !errors.Is(err, ErrIndexHeadDoesNotExist) {
+		return nil, nil, err
 	}
+	for location, option := range i.locations {
+		if _, ok := locations[location]; ok {
+			continue
+		}
+		generation, ok := head.Locations[location]
+		if !ok || head.compatible(i.analyzer) != nil {
+			locations[location] = option
+			continue
+		}
+		generations[location] = generation
+	}
+	return generations, locations, nil
+}
 
-	for path, option := range i.locations {
-		indexWalkFunc := newIndexWalkFunc(builder)
+// Reindex incrementally updates currently opened index in place, instead of creating new index from scratch
+// Added, changed and removed paths since last index are detected with manifests stored within the index,
+// and only the deltas are applied as a single batch of each sub-index, such that searches never see a half-updated sub-index
+// No new generation is created, so other generations sharing the sub-indexes are marked as modified, which could not be rolled back to
+// If opened index has no manifest, ErrIndexManifestDoesNotExist will be returned, and Index should be used instead
+func (i *Indexer) Reindex() (Delta, error) {
+	delta, _, err := i.ReindexWithReport()
+	return delta, err
+}
 
+// ReindexWithReport incrementally updates currently opened index as Reindex does, along with report of paths walked and skipped
+// Paths which could not be walked keep their previous state, such that temporary errors will not remove them from the index
+func (i *Indexer) ReindexWithReport() (Delta, *IndexReport, error) {
+	return i.ReindexContext(context.Background(), IndexOptions{})
+}
+
+// ReindexContext incrementally updates currently opened index as ReindexWithReport does, where progress is reported with options
+// If context is done before the changes are applied, the index is left unchanged and the context error is returned
+func (i *Indexer) ReindexContext(ctx context.Context, opts IndexOptions) (Delta, *IndexReport, error) {
+	i.batchMutex.Lock()
+	defer i.batchMutex.Unlock()
+
+	i.mutex.RLock()
+	if i.index == nil || !i.open {
+		i.mutex.RUnlock()
+		return Delta{}, nil, ErrIndexNotOpened
+	}
+	name := i.index.name()
+	indexes, prev, err := i.locationIndexes()
+	i.mutex.RUnlock()
+	if err != nil {
+		return Delta{}, nil, err
+	}
+	for location := range i.locations {
+		if _, ok := indexes[location]; !ok {
+			return Delta{}, nil, fmt.Errorf("%w: location %v is not indexed yet", ErrIndexManifestDoesNotExist, location)
+		}
+	}
+	for location := range indexes {
+		if _, ok := i.locations[location]; !ok {
+			return Delta{}, nil, fmt.Errorf("%w: location %v is no longer configured", ErrIndexManifestDoesNotExist, location)
+		}
+	}
+
+	// walk without holding lock, as it could take long for large locations
+	r := newReporter(i.failFast)
+	t := newTracker()
+	stop := t.report(opts)
+	batches := make(map[string]*bleve.Batch, len(indexes))
+	manifests := make(map[string]manifest, len(indexes))
+	for location, index := range indexes {
+		batches[location] = index.NewBatch()
+		manifests[location] = make(manifest)
+	}
+	var mutex sync.Mutex
+	err = i.walkLocations(ctx, i.locations, r, t, func(path string, option LocationOption) (walker.WalkFunc, error) {
 		filtersWalkFunc, err := newFiltersWalkFunc(path, option)
-		// TODO: change to not fail fast
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
-		// combine index walkFunc last
-		fn := walker.Chain(filtersWalkFunc, indexWalkFunc)
+		// batches and manifests are shared across locations, which are not concurrent-safe
+		m := manifests[path]
+		indexWalkFunc := withChangedOnly(prev[path], m, newIndexWalkFunc(batches[path], path))
+		return walker.Chain(filtersWalkFunc, walker.Synchronized(&mutex, walker.Chain(newManifestWalkFunc(m), indexWalkFunc))), nil
+	})
+	stop()
+	if err != nil {
+		return Delta{}, nil, err
+	}
 
-		err = walker.Walk(path, fn)
-		// TODO: change to not fail fast
+	var delta Delta
+	for location, m := range manifests {
+		carryOver(prev[location], m, r.skipped(location))
+		d, removed := diffManifests(prev[location], m)
+		delta.Added += d.Added
+		delta.Changed += d.Changed
+		delta.Removed += d.Removed
+		for _, path := range removed {
+			batches[location].Delete(path)
+		}
+		err = writeManifests(batches[location], map[string]manifest{location: prev[location]}, map[string]manifest{location: m})
 		if err != nil {
-			return "", fmt.Errorf("failed to traverse path: %w", err)
+			return Delta{}, nil, err
+		}
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return Delta{}, nil, err
+	}
+
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	if i.index == nil || !i.open {
+		return Delta{}, nil, ErrIndexNotOpened
+	}
+	if i.index.name() != name {
+		return Delta{}, nil, ErrIndexSwapped
+	}
+	if delta != (Delta{}) {
+		err = i.markModified(name, generationsOf(indexes))
+		if err != nil {
+			return Delta{}, nil, err
+		}
+	}
+	for location, batch := range batches {
+		err = indexes[location].Batch(batch)
+		if err != nil {
+			return Delta{}, nil, fmt.Errorf("failed to execute reindex batch of %v: %w", location, err)
+		}
+	}
+	return delta, r.result(), nil
+}
+
+// locationIndexes returns opened sub-index of each location, along with their manifests
+// Caller should acquire Read lock of mutex, and single index of older versions should be rebuilt to be updated
+func (i *Indexer) locationIndexes() (map[string]bleve.Index, map[string]manifest, error) {
+	if i.index.legacy() {
+		return nil, nil, fmt.Errorf("%w: index of older versions should be rebuilt", ErrIndexManifestDoesNotExist)
+	}
+	indexes := make(map[string]bleve.Index, len(i.index.indexes))
+	manifests := make(map[string]manifest, len(i.index.indexes))
+	for location, index := range i.index.indexes {
+		ms, err := readManifests(index)
+		if err != nil {
+			return nil, nil, err
+		}
+		m := ms[location]
+		if m == nil {
+			m = make(manifest)
+		}
+		indexes[location] = index
+		manifests[location] = m
+	}
+	return indexes, manifests, nil
+}
+
+// walkLocations walks locations concurrently with workers shared across them, where errors are collected by reporter
+// WalkFunc of each location is created by newWalkFunc before walking, and it must be concurrent-safe
+// Location is skipped if its WalkFunc could not be created or its root could not be walked, unless fail fast
+// Walks are stopped once context is done, and the context error is returned
+func (i *Indexer) walkLocations(ctx context.Context, locations map[string]LocationOption, r *reporter, t *tracker, newWalkFunc func(path string, option LocationOption) (walker.WalkFunc, error)) error {
+	walkFuncs := make(map[string]walker.WalkFunc, len(locations))
+	for path, option := range locations {
+		fn, err := newWalkFunc(path, option)
+		if err != nil {
+			err = r.fail(path, path, FilterError, err)
+			if !errors.Is(err, walker.SkipThis) {
+				return err
+			}
+			continue
+		}
+		fn = walker.Chain(i.skipNestedWalkFunc(path), fn)
+		walkFuncs[path] = withContext(ctx, t.walkFunc(path, r.walkFunc(path, fn)))
+	}
+
+	pool := walker.NewPool(i.concurrency)
+	errs := make(chan error, len(walkFuncs))
+	for path, fn := range walkFuncs {
+		go func(path string, fn walker.WalkFunc) {
+			err := pool.Walk(path, fn)
+			var e *PathError
+			if err != nil && ctx.Err() == nil && !errors.As(err, &e) {
+				// root of location could not be walked
+				err = r.fail(path, path, errorKind(err), err)
+				if errors.Is(err, walker.SkipThis) {
+					err = nil
+				}
+			}
+			errs <- err
+		}(path, fn)
+	}
+	var first error
+	for range walkFuncs {
+		err := <-errs
+		if err != nil && first == nil {
+			first = fmt.Errorf("failed to traverse path: %w", err)
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return first
+}
+
+// skipNestedWalkFunc skips other locations nested within location, as path is owned by its nearest location
+// Such that each path is indexed into sub-index of a single location only
+func (i *Indexer) skipNestedWalkFunc(location string) walker.WalkFunc {
+	return func(path string, info walker.FileInfo, err error) error {
+		if err != nil {
+			return err
 		}
+		if _, ok := i.locations[path]; ok && path != location {
+			return walker.SkipThis
+		}
+		return nil
 	}
+}
 
-	err = builder.Close()
+// update applies changes made by fn to sub-index of each location as a batch, along with its manifest
+// Manifests passed to fn could be modified in place, and they will be written back within the same batch
+// Sub-indexes are updated in place as Reindex does, where other generations sharing them are marked as modified
+func (i *Indexer) update(fn func(batches map[string]*bleve.Batch, manifests map[string]manifest) error) error {
+	i.batchMutex.Lock()
+	defer i.batchMutex.Unlock()
+
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	if i.index == nil || !i.open {
+		return ErrIndexNotOpened
+	}
+	indexes, prev, err := i.locationIndexes()
 	if err != nil {
-		return "", fmt.Errorf("failed to execute index batch: %w", err)
+		return err
+	}
+	batches := make(map[string]*bleve.Batch, len(indexes))
+	manifests := make(map[string]manifest, len(prev))
+	for location, index := range indexes {
+		batches[location] = index.NewBatch()
+		m := make(manifest, len(prev[location]))
+		for path, mtime := range prev[location] {
+			m[path] = mtime
+		}
+		manifests[location] = m
+	}
+
+	err = fn(batches, manifests)
+	if err != nil {
+		return err
 	}
-	return name, nil
+	err = i.markModified(i.index.name(), generationsOf(indexes))
+	if err != nil {
+		return err
+	}
+	for location, batch := range batches {
+		err = writeManifests(batch, map[string]manifest{location: prev[location]}, map[string]manifest{location: manifests[location]})
+		if err != nil {
+			return err
+		}
+		err = indexes[location].Batch(batch)
+		if err != nil {
+			return fmt.Errorf("failed to execute update batch of %v: %w", location, err)
+		}
+	}
+	return nil
 }
 
 // DocCount returns number of documents stored within the index
@@ -185,32 +734,82 @@ func (i *Indexer) DocCount() (uint64, error) {
 	return i.index.docCount()
 }
 
-// Search index with specified term and returns search result accordingly
+// Search index with specified term and returns search result accordingly, with DefaultSearchOptions
 func (i *Indexer) Search(term string) (*bleve.SearchResult, error) {
+	return i.SearchWithOptions(term, DefaultSearchOptions())
+}
+
+// SearchWithOptions searches index with specified term and options, and returns search result accordingly
+// Hits are ranked with bonuses of paths and frecency of selections as well if PathBonus and FrecencyWeight are specified, unless sort order is specified
+// Only the top hits within rank window are reranked, and the rest follow in order of text score
+func (i *Indexer) SearchWithOptions(term string, opts SearchOptions) (*bleve.SearchResult, error) {
+	req, err := newSearchRequest(term, opts, i.analyzer)
+	if err != nil {
+		return nil, err
+	}
+	rerank := (opts.PathBonus > 0 || opts.FrecencyWeight > 0) && len(opts.Sort) == 0
+	if rerank {
+		req.From = 0
+		req.Size = rankWindow
+	}
+
+	res, err := i.search(req)
+	if err != nil {
+		return nil, err
+	}
+	if rerank {
+		if opts.PathBonus > 0 {
+			rankPaths(res, term, opts.PathBonus)
+		}
+		var frecencies map[string]float64
+		if opts.FrecencyWeight > 0 {
+			frecencies, err = i.history.frecencies(time.Now())
+			if err != nil {
+				return nil, err
+			}
+		}
+		// hits are only sliced without frecencies
+		blendFrecency(res, frecencies, opts.FrecencyWeight, opts.Offset, opts.Limit)
+
+		// hits beyond the window are not reranked, such that pages are taken from the same order regardless of offset
+		end := opts.Offset + opts.Limit
+		if end > rankWindow && res.Total > rankWindow {
+			req.From = opts.Offset
+			if req.From < rankWindow {
+				req.From = rankWindow
+			}
+			req.Size = end - req.From
+			rest, err := i.search(req)
+			if err != nil {
+				return nil, err
+			}
+			res.Hits = append(res.Hits, rest.Hits...)
+		}
+	}
+	if opts.Highlight && i.analyzer.ASCIIFolding {
+		unfoldLocations(res)
+	}
+	return res, nil
+}
+
+func (i *Indexer) search(req *bleve.SearchRequest) (*bleve.SearchResult, error) {
 	i.mutex.RLock()
 	defer i.mutex.RUnlock()
 
 	if i.index == nil || !i.open {
 		return nil, ErrIndexNotOpened
 	}
-	// TODO: may have configuration to allow tweak of these settings
-	queryString := bleve.NewQueryStringQuery(term)
-
-	fuzzy := bleve.NewFuzzyQuery(term)
-	fuzzy.SetBoost(2)
-
-	wildcard := bleve.NewWildcardQuery(term)
-	wildcard.SetBoost(2)
-
-	prefix := bleve.NewPrefixQuery(term)
-	prefix.SetBoost(2)
+	return i.index.search(req)
+}
 
-	match := bleve.NewMatchQuery(term)
-	match.SetBoost(5)
+// RecordSelection records path as selected, such that it will be ranked higher with frecency for later searches
+func (i *Indexer) RecordSelection(path string) error {
+	return i.history.record(filepath.Clean(path), time.Now())
+}
 
-	union := bleve.NewDisjunctionQuery(fuzzy, prefix, queryString, wildcard, match)
-	req := bleve.NewSearchRequest(union)
-	return i.index.search(req)
+// PruneHistory removes selections of paths that no longer exist, and returns number of removed selections
+func (i *Indexer) PruneHistory() (int, error) {
+	return i.history.prune()
 }
 
 // SearchWith for custom bleve search request for the underlying index
@@ -218,6 +817,9 @@ func (i *Indexer) SearchWith(req *bleve.SearchRequest) (*bleve.SearchResult, err
 	i.mutex.RLock()
 	defer i.mutex.RUnlock()
 
+	if i.index == nil || !i.open {
+		return nil, ErrIndexNotOpened
+	}
 	return i.index.search(req)
 }
 
@@ -263,12 +865,37 @@ func (i *Indexer) Close() error {
 	if err != nil {
 		return err
 	}
-
 	name := i.index.name()
-	err = removeIndexesExclude(i.basePath, name)
+	used := i.index.generations()
+	i.index = nil
+	i.open = false
+	err = i.genLocks.release(used...)
+	if err != nil {
+		return err
+	}
+
+	// indexes in use by others are kept, where unused indexes are removed once others have finished with the base path
+	_, err = i.lock.tryExclusive(func() error {
+		// index specified by HEAD file could be written by others after this index is opened
+		head, err := readHead(i.basePath)
+		if errors.Is(err, ErrIndexHeadCorrupted) {
+			// index specified by HEAD file is unknown, so all indexes are kept for it to be recovered
+			return nil
+		}
+		if err != nil && !errors.Is(err, ErrIndexHeadDoesNotExist) {
+			return err
+		}
+		gens, err := readGenerations(i.basePath)
+		if err != nil {
+			return err
+		}
+		keep := append(retainedGenerations(gens, i.retention), name, head.Name)
+		keep = append(referencedGenerations(gens, keep), used...)
+		_, err = removeIndexesExclude(i.basePath, keep...)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to remove unused indexes: %w", err)
 	}
-	i.open = false
 	return nil
 }
@@ -1,14 +1,18 @@
 package fzd_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/blevesearch/bleve/v2"
+	"github.com/google/uuid"
 	"github.com/horacehylee/fzd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -245,203 +249,1291 @@ func (suite *FzdTestSuite) TestOpenAndSwapProducesHeadFileAndLeaveOtherIndexes()
 	assert.Equal(t, name1, indexName)
 }
 
+func (suite *FzdTestSuite) TestOpenAndSwapWritesHeadManifest() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	before := time.Now()
+	name := suite.indexAndOpen()
+
+	head := suite.readHead()
+	assert.Equal(t, name, head.Name)
+	assert.False(t, head.Created.Before(before))
+	assert.Equal(t, fzd.Version, head.Version)
+	assert.Equal(t, fzd.MappingVersion, head.MappingVersion)
+	assert.NotEmpty(t, head.LocationsHash)
+	assert.Equal(t, uint64(6), head.DocCount)
+
+	actual, err := indexer.Head()
+	assert.NoError(t, err)
+	assert.Equal(t, head.Name, actual.Name)
+	assert.Equal(t, head.DocCount, actual.DocCount)
+}
+
+func (suite *FzdTestSuite) TestOpenWithHeadFileOfOlderVersion() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	name := suite.indexAndOpen()
+	err := indexer.Close()
+	assert.NoError(t, err)
+
+	// HEAD file of older versions only contains index name
+	err = os.WriteFile(filepath.Join(suite.indexesDir, fzd.HeadFileName), []byte(name), fileMode)
+	assert.NoError(t, err)
+
+	indexer, err = fzd.NewIndexer(suite.indexesDir)
+	assert.NoError(t, err)
+	suite.indexer = indexer
+	err = indexer.Open()
+	assert.NoError(t, err)
+
+	indexName, err := indexer.IndexName()
+	assert.NoError(t, err)
+	assert.Equal(t, name, indexName)
+}
+
+func (suite *FzdTestSuite) TestOpenFailedIfHeadFileCorrupted() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+	err := indexer.Close()
+	assert.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(suite.indexesDir, fzd.HeadFileName), []byte(`{"name": "trunc`), fileMode)
+	assert.NoError(t, err)
+
+	indexer, err = fzd.NewIndexer(suite.indexesDir)
+	assert.NoError(t, err)
+	suite.indexer = indexer
+	err = indexer.Open()
+	assert.ErrorIs(t, err, fzd.ErrIndexHeadCorrupted)
+}
+
+func (suite *FzdTestSuite) TestOpenFailedIfIncompatible() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	name := suite.indexAndOpen()
+	err := indexer.Close()
+	assert.NoError(t, err)
+
+	content, err := json.Marshal(fzd.Head{Name: name, MappingVersion: fzd.MappingVersion + 1})
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(suite.indexesDir, fzd.HeadFileName), content, fileMode)
+	assert.NoError(t, err)
+
+	indexer, err = fzd.NewIndexer(suite.indexesDir)
+	assert.NoError(t, err)
+	suite.indexer = indexer
+	err = indexer.Open()
+	assert.ErrorIs(t, err, fzd.ErrIndexIncompatible)
+}
+
 func (suite *FzdTestSuite) TestOpenAndSwapForSameIndex() {
 	t := suite.T()
 	indexer := suite.indexer
 
-	name1, err := indexer.Index()
+	name1, err := indexer.Index()
+	assert.NoError(t, err)
+
+	err = indexer.OpenAndSwap(name1)
+	assert.NoError(t, err)
+
+	headFileIndexName1 := suite.readHeadFile()
+	assert.Equal(t, name1, headFileIndexName1)
+
+	headFileModTime1 := suite.headFileModTime()
+
+	dirnames1 := suite.readIndexesDirnames(2)
+	assert.ElementsMatch(t, []string{
+		fzd.HeadFileName,
+		headFileIndexName1,
+	}, dirnames1)
+
+	name2, err := indexer.Index()
+	assert.NoError(t, err)
+
+	err = indexer.OpenAndSwap(name1)
+	assert.NoError(t, err)
+
+	headFileIndexName2 := suite.readHeadFile()
+	assert.Equal(t, name1, headFileIndexName2)
+
+	headFileModTime2 := suite.headFileModTime()
+	assert.Equal(t, headFileModTime1, headFileModTime2, fmt.Sprintf("%v file should not be updated if OpenAndSwap with same index", fzd.HeadFileName))
+
+	dirnames2 := suite.readIndexesDirnames(3)
+	assert.ElementsMatch(t, []string{
+		fzd.HeadFileName,
+		headFileIndexName2,
+		name2,
+	}, dirnames2, "other indexes will not be removed for OpenAndSwap")
+
+	indexName, err := indexer.IndexName()
+	assert.NoError(t, err)
+	assert.Equal(t, name1, indexName)
+}
+
+func (suite *FzdTestSuite) TestDocCount() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	count, err := indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), count)
+}
+
+func (suite *FzdTestSuite) TestDocCountReturnsErrorIfNotOpened() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	_, err := indexer.DocCount()
+	assert.ErrorIs(t, err, fzd.ErrIndexNotOpened)
+}
+
+func (suite *FzdTestSuite) TestSearch() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	res, err := indexer.Search("txt")
+	assert.NoError(t, err)
+
+	hits := suite.readSearchResults(res, 3)
+	assert.Equal(t, []string{
+		suite.level0File,
+		suite.level1File,
+		suite.level2File,
+	}, hits)
+}
+
+func (suite *FzdTestSuite) TestSearchWithOptions() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	opts := fzd.DefaultSearchOptions()
+	opts.Limit = 2
+	opts.Sort = []string{fzd.FieldName}
+	res, err := indexer.SearchWithOptions("txt", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), res.Total)
+
+	hits := suite.readSearchResults(res, 2)
+	assert.Equal(t, []string{
+		suite.level0File,
+		suite.level1File,
+	}, hits)
+
+	opts = fzd.DefaultSearchOptions()
+	opts.Filters = map[string]string{fzd.FieldDir: suite.level1Dir}
+	res, err = indexer.SearchWithOptions("txt", opts)
+	assert.NoError(t, err)
+
+	hits = suite.readSearchResults(res, 1)
+	assert.Equal(t, []string{
+		suite.level1File,
+	}, hits)
+}
+
+func (suite *FzdTestSuite) TestSearchWithOffsetPagesStably() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	search := func(offset int, limit int) ([]string, uint64) {
+		opts := fzd.DefaultSearchOptions()
+		opts.Offset = offset
+		opts.Limit = limit
+		// hits of the same type are ordered by path
+		opts.Sort = []string{fzd.FieldIsDir}
+		res, err := indexer.SearchWithOptions("", opts)
+		assert.NoError(t, err)
+		var hits []string
+		for _, h := range res.Hits {
+			hits = append(hits, h.ID)
+		}
+		return hits, res.Total
+	}
+
+	all, total := search(0, 10)
+	assert.Equal(t, uint64(6), total)
+	assert.Equal(t, []string{
+		suite.level0File,
+		suite.level1File,
+		suite.level2File,
+		suite.level0Dir,
+		suite.level1Dir,
+		suite.level2Dir,
+	}, all)
+
+	var pages []string
+	for offset := 0; offset < len(all); offset += 4 {
+		page, total := search(offset, 4)
+		assert.Equal(t, uint64(6), total)
+		pages = append(pages, page...)
+	}
+	assert.Equal(t, all, pages)
+}
+
+func (suite *FzdTestSuite) TestSearchWithOffsetPagesStablyBeyondRankWindow() {
+	t := suite.T()
+
+	dir, err := os.MkdirTemp("", "testFzdPages")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for i := 0; i < 150; i++ {
+		err = os.WriteFile(filepath.Join(dir, fmt.Sprintf("report%03d.txt", i)), []byte("content"), fileMode)
+		assert.NoError(t, err)
+	}
+
+	err = suite.indexer.Close()
+	assert.NoError(t, err)
+	indexer, err := fzd.NewIndexer(suite.indexesDir, fzd.WithLocation(dir, fzd.LocationOption{}))
+	assert.NoError(t, err)
+	suite.indexer = indexer
+	suite.indexAndOpen()
+
+	search := func(offset int, limit int) ([]string, uint64) {
+		opts := fzd.DefaultSearchOptions()
+		opts.Offset = offset
+		opts.Limit = limit
+		res, err := indexer.SearchWithOptions("report", opts)
+		assert.NoError(t, err)
+		var hits []string
+		for _, h := range res.Hits {
+			hits = append(hits, h.ID)
+		}
+		return hits, res.Total
+	}
+
+	all, total := search(0, 200)
+	assert.Equal(t, 150, len(all))
+	assert.Equal(t, uint64(150), total)
+
+	// selected path beyond the window would be ranked to top only for pages reaching it
+	err = indexer.RecordSelection(all[len(all)-1])
+	assert.NoError(t, err)
+	all, _ = search(0, 200)
+
+	var pages []string
+	for offset := 0; offset < len(all); offset += 7 {
+		page, _ := search(offset, 7)
+		pages = append(pages, page...)
+	}
+	assert.Equal(t, all, pages)
+}
+
+func (suite *FzdTestSuite) TestSearchWithFrecency() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	err := indexer.RecordSelection(suite.level2File)
+	assert.NoError(t, err)
+
+	opts := fzd.DefaultSearchOptions()
+	opts.FrecencyWeight = 1
+	res, err := indexer.SearchWithOptions("txt", opts)
+	assert.NoError(t, err)
+
+	hits := suite.readSearchResults(res, 3)
+	assert.Equal(t, suite.level2File, hits[0])
+
+	opts.FrecencyWeight = 0
+	res, err = indexer.SearchWithOptions("txt", opts)
+	assert.NoError(t, err)
+
+	hits = suite.readSearchResults(res, 3)
+	assert.Equal(t, suite.level0File, hits[0])
+}
+
+func (suite *FzdTestSuite) TestCloseKeepsHistory() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	name := suite.indexAndOpen()
+
+	err := indexer.RecordSelection(suite.level2File)
+	assert.NoError(t, err)
+
+	err = indexer.Close()
+	assert.NoError(t, err)
+
+	dirnames := suite.readIndexesDirnames(3)
+	assert.ElementsMatch(t, []string{
+		fzd.HeadFileName,
+		fzd.HistoryFileName,
+		name,
+	}, dirnames)
+}
+
+func (suite *FzdTestSuite) TestSearchWithFieldsAndHighlight() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	opts := fzd.DefaultSearchOptions()
+	opts.Fields = []string{"*"}
+	opts.Highlight = true
+	opts.Filters = map[string]string{fzd.FieldExt: "txt"}
+	res, err := indexer.SearchWithOptions("level1", opts)
+	assert.NoError(t, err)
+
+	hits := fzd.NewHits(res)
+	assert.Equal(t, suite.level1File, hits[0].Path)
+	assert.Equal(t, "level1.txt", hits[0].Name)
+	assert.Equal(t, suite.level1Dir, hits[0].Dir)
+	assert.Equal(t, int64(len("content")), hits[0].Size)
+	assert.Equal(t, suite.level0Dir, hits[0].Location)
+	assert.False(t, hits[0].ModTime.IsZero())
+	assert.Contains(t, hits[0].Fragments[fzd.FieldPath][0], "<mark>level1</mark>")
+	for _, m := range hits[0].Matches {
+		assert.Equal(t, "level1", hits[0].Path[m.Start:m.End])
+	}
+	assert.Equal(t, "<level1>", hits[0].Highlight("<", ">")[len(suite.level0Dir)+1:len(suite.level0Dir)+9])
+}
+
+func (suite *FzdTestSuite) TestSearchWithFilters() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	search := func(query string) []string {
+		opts := fzd.DefaultSearchOptions()
+		opts.Limit = 10
+		term, err := fzd.ParseQuery(query, &opts)
+		assert.NoError(t, err)
+		res, err := indexer.SearchWithOptions(term, opts)
+		assert.NoError(t, err)
+		var hits []string
+		for _, h := range res.Hits {
+			hits = append(hits, h.ID)
+		}
+		sort.Strings(hits)
+		return hits
+	}
+
+	// location itself is indexed as well
+	assert.Equal(t, []string{suite.level0Dir, suite.level1Dir, suite.level2Dir}, search("type:dir"))
+	assert.Equal(t, []string{suite.level1File, suite.level2File}, search("ext:txt in:"+suite.level1Dir))
+	// level1 is matched by fuzzy query of level2
+	assert.Equal(t, []string{suite.level1File, suite.level2Dir, suite.level2File}, search("level2 in:"+suite.level1Dir))
+	assert.Equal(t, []string{suite.level0File, suite.level1File, suite.level2File}, search("type:file mtime:<1h"))
+	assert.Empty(t, search("type:file mtime:>1h"))
+}
+
+func (suite *FzdTestSuite) TestSearchWithOptionsReturnsErrorIfInvalid() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	opts := fzd.DefaultSearchOptions()
+	opts.Queries = nil
+	_, err := indexer.SearchWithOptions("txt", opts)
+	assert.Error(t, err)
+}
+
+func (suite *FzdTestSuite) TestSearchWith() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	req := bleve.NewSearchRequest(bleve.NewWildcardQuery("*txt"))
+	res, err := indexer.SearchWith(req)
+	assert.NoError(t, err)
+
+	hits := suite.readSearchResults(res, 3)
+	assert.Equal(t, []string{
+		suite.level0File,
+		suite.level1File,
+		suite.level2File,
+	}, hits)
+}
+
+func (suite *FzdTestSuite) TestSearchWithDocumentFields() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	ext := bleve.NewTermQuery("txt")
+	ext.SetField(fzd.FieldExt)
+	res, err := indexer.SearchWith(bleve.NewSearchRequest(ext))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		suite.level0File,
+		suite.level1File,
+		suite.level2File,
+	}, suite.readSearchResults(res, 3))
+
+	isDir := bleve.NewBoolFieldQuery(true)
+	isDir.SetField(fzd.FieldIsDir)
+	res, err = indexer.SearchWith(bleve.NewSearchRequest(isDir))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		suite.level0Dir,
+		suite.level1Dir,
+		suite.level2Dir,
+	}, suite.readSearchResults(res, 3))
+
+	dir := bleve.NewTermQuery(suite.level1Dir)
+	dir.SetField(fzd.FieldDir)
+	res, err = indexer.SearchWith(bleve.NewSearchRequest(dir))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		suite.level1File,
+		suite.level2Dir,
+	}, suite.readSearchResults(res, 2))
+
+	size := float64(len("content"))
+	inclusive := true
+	sizeQuery := bleve.NewNumericRangeInclusiveQuery(&size, &size, &inclusive, &inclusive)
+	sizeQuery.SetField(fzd.FieldSize)
+	req := bleve.NewSearchRequest(sizeQuery)
+	req.Fields = []string{fzd.FieldName, fzd.FieldModTime, fzd.FieldLocation}
+	res, err = indexer.SearchWith(req)
+	assert.NoError(t, err)
+	suite.readSearchResults(res, 3)
+	for _, h := range res.Hits {
+		assert.Equal(t, filepath.Base(h.ID), h.Fields[fzd.FieldName])
+		assert.Equal(t, suite.level0Dir, h.Fields[fzd.FieldLocation])
+		assert.NotEmpty(t, h.Fields[fzd.FieldModTime])
+	}
+}
+
+func (suite *FzdTestSuite) TestSearchAndDocCountAfterIndexSwapped() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	name1 := suite.indexAndOpen()
+
+	indexName1, err := indexer.IndexName()
+	assert.NoError(t, err)
+	assert.Equal(t, name1, indexName1)
+
+	count1, err := indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), count1)
+
+	res1, err := indexer.Search("txt")
+	assert.NoError(t, err)
+
+	hits1 := suite.readSearchResults(res1, 3)
+	assert.Equal(t, []string{
+		suite.level0File,
+		suite.level1File,
+		suite.level2File,
+	}, hits1)
+
+	time1, err := indexer.LastIndexed()
+	assert.NoError(t, err)
+
+	extraFile := filepath.Join(suite.level0Dir, "extra.txt")
+	err = os.WriteFile(extraFile, []byte("content"), fileMode)
+	assert.NoError(t, err)
+	defer os.Remove(extraFile)
+
+	time.Sleep(1 * time.Second)
+
+	name2 := suite.indexAndOpen()
+	assert.NotEqual(t, name2, name1)
+
+	indexName2, err := indexer.IndexName()
+	assert.NoError(t, err)
+	assert.Equal(t, name2, indexName2)
+
+	count2, err := indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), count2)
+
+	res2, err := indexer.Search("txt")
+	assert.NoError(t, err)
+
+	hits2 := suite.readSearchResults(res2, 4)
+	assert.ElementsMatch(t, []string{
+		extraFile,
+		suite.level0File,
+		suite.level1File,
+		suite.level2File,
+	}, hits2)
+
+	time2, err := indexer.LastIndexed()
+	assert.NoError(t, err)
+	assert.Greater(t, time2.Unix(), time1.Unix())
+}
+
+func (suite *FzdTestSuite) TestSearchReturnsErrorIfNotOpened() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	_, err := indexer.Search("txt")
+	assert.ErrorIs(t, err, fzd.ErrIndexNotOpened)
+}
+
+func (suite *FzdTestSuite) TestReindex() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	name := suite.indexAndOpen()
+
+	delta, err := indexer.Reindex()
+	assert.NoError(t, err)
+	assert.Equal(t, fzd.Delta{}, delta, "nothing should be changed")
+
+	extraFile := filepath.Join(suite.level1Dir, "extra.txt")
+	err = os.WriteFile(extraFile, []byte("content"), fileMode)
+	assert.NoError(t, err)
+	defer os.Remove(extraFile)
+
+	delta, err = indexer.Reindex()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delta.Added)
+	assert.Equal(t, 0, delta.Removed)
+
+	count, err := indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), count)
+
+	res, err := indexer.Search("txt")
+	assert.NoError(t, err)
+	hits := suite.readSearchResults(res, 4)
+	assert.Contains(t, hits, extraFile)
+
+	err = os.Remove(extraFile)
+	assert.NoError(t, err)
+
+	delta, err = indexer.Reindex()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delta.Added)
+	assert.Equal(t, 1, delta.Removed)
+
+	count, err = indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), count)
+
+	indexName, err := indexer.IndexName()
+	assert.NoError(t, err)
+	assert.Equal(t, name, indexName, "index should be updated in place")
+	suite.readIndexesDirnames(2)
+}
+
+func (suite *FzdTestSuite) TestIndexAndReindexWithConcurrency() {
+	t := suite.T()
+
+	for _, concurrency := range []int{1, 4} {
+		err := suite.indexer.Close()
+		assert.NoError(t, err)
+		indexer, err := fzd.NewIndexer(suite.indexesDir,
+			fzd.WithLocation(suite.level1Dir, fzd.LocationOption{}),
+			fzd.WithLocation(suite.level2Dir, fzd.LocationOption{}),
+			fzd.WithConcurrency(concurrency),
+		)
+		assert.NoError(t, err)
+		suite.indexer = indexer
+
+		suite.indexAndOpen()
+
+		count, err := indexer.DocCount()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(4), count, "concurrency %v", concurrency)
+
+		delta, err := indexer.Reindex()
+		assert.NoError(t, err)
+		assert.Equal(t, fzd.Delta{}, delta, "concurrency %v", concurrency)
+	}
+}
+
+func (suite *FzdTestSuite) TestIndexWithReportSkipsErroneousLocations() {
+	t := suite.T()
+
+	missing := filepath.Join(suite.level0Dir, "missing")
+	err := suite.indexer.Close()
+	assert.NoError(t, err)
+	indexer, err := fzd.NewIndexer(suite.indexesDir,
+		fzd.WithLocation(suite.level1Dir, fzd.LocationOption{}),
+		fzd.WithLocation(suite.level2Dir, fzd.LocationOption{Filters: []fzd.Filter{"invalid"}}),
+		fzd.WithLocation(missing, fzd.LocationOption{}),
+	)
+	assert.NoError(t, err)
+	suite.indexer = indexer
+
+	name, report, err := indexer.IndexWithReport()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, name)
+	// paths within nested location are owned by it, so they are not indexed by outer location either
+	assert.Equal(t, map[string]fzd.LocationReport{
+		suite.level1Dir: {Paths: 2},
+		suite.level2Dir: {Skipped: 1},
+		missing:         {Skipped: 1},
+	}, report.Locations)
+	assert.Equal(t, map[fzd.ErrorKind]int{
+		fzd.FilterError:   1,
+		fzd.NotExistError: 1,
+	}, report.ErrorsByKind())
+}
+
+func (suite *FzdTestSuite) TestIndexWithFailFast() {
+	t := suite.T()
+
+	missing := filepath.Join(suite.level0Dir, "missing")
+	err := suite.indexer.Close()
+	assert.NoError(t, err)
+	indexer, err := fzd.NewIndexer(suite.indexesDir,
+		fzd.WithLocation(suite.level1Dir, fzd.LocationOption{}),
+		fzd.WithLocation(missing, fzd.LocationOption{}),
+		fzd.WithFailFast(true),
+	)
+	assert.NoError(t, err)
+	suite.indexer = indexer
+
+	_, err = indexer.Index()
+	var e *fzd.PathError
+	if assert.ErrorAs(t, err, &e) {
+		assert.Equal(t, missing, e.Path)
+		assert.Equal(t, fzd.NotExistError, e.Kind)
+	}
+}
+
+func (suite *FzdTestSuite) TestReindexWithReportKeepsSkippedPaths() {
+	t := suite.T()
+
+	dir, err := os.MkdirTemp("", "testFzdSkipped")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "skipped.txt")
+	err = os.WriteFile(file, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	err = suite.indexer.Close()
+	assert.NoError(t, err)
+	indexer, err := fzd.NewIndexer(suite.indexesDir,
+		fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}),
+		fzd.WithLocation(dir, fzd.LocationOption{}),
+	)
+	assert.NoError(t, err)
+	suite.indexer = indexer
+	suite.indexAndOpen()
+
+	// location is temporarily unavailable
+	moved := dir + ".moved"
+	err = os.Rename(dir, moved)
+	assert.NoError(t, err)
+	defer os.RemoveAll(moved)
+
+	delta, report, err := indexer.ReindexWithReport()
+	assert.NoError(t, err)
+	assert.Equal(t, fzd.Delta{}, delta, "paths of skipped location should not be removed")
+	if assert.Len(t, report.Errors, 1) {
+		assert.Equal(t, dir, report.Errors[0].Path)
+		assert.Equal(t, fzd.NotExistError, report.Errors[0].Kind)
+	}
+
+	count, err := indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(8), count)
+
+	err = os.Rename(moved, dir)
+	assert.NoError(t, err)
+
+	delta, report, err = indexer.ReindexWithReport()
+	assert.NoError(t, err)
+	assert.Equal(t, fzd.Delta{}, delta)
+	assert.Empty(t, report.Errors)
+}
+
+func (suite *FzdTestSuite) TestReindexReturnsErrorIfNotOpened() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	_, err := indexer.Reindex()
+	assert.ErrorIs(t, err, fzd.ErrIndexNotOpened)
+}
+
+func (suite *FzdTestSuite) TestIndexContextCancelled() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := indexer.IndexContext(ctx, fzd.IndexOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// partially built index is removed
+	suite.readIndexesDirnames(0)
+}
+
+func (suite *FzdTestSuite) TestIndexContextReportsProgress() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	var progresses []fzd.Progress
+	_, _, err := indexer.IndexContext(context.Background(), fzd.IndexOptions{
+		Progress: func(p fzd.Progress) {
+			progresses = append(progresses, p)
+		},
+		ProgressInterval: time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	if assert.NotEmpty(t, progresses) {
+		last := progresses[len(progresses)-1]
+		assert.Equal(t, int64(3), last.Dirs)
+		assert.Equal(t, int64(3), last.Files)
+		assert.Equal(t, suite.level0Dir, last.Location)
+		assert.Greater(t, last.Elapsed, time.Duration(0))
+	}
+	for n := 1; n < len(progresses); n++ {
+		assert.LessOrEqual(t, progresses[n-1].Files, progresses[n].Files)
+	}
+}
+
+func (suite *FzdTestSuite) TestReindexContextCancelled() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+	extraFile := filepath.Join(suite.level0Dir, "extra.txt")
+	err := os.WriteFile(extraFile, []byte("content"), fileMode)
+	assert.NoError(t, err)
+	defer os.Remove(extraFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = indexer.ReindexContext(ctx, fzd.IndexOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// index is left unchanged, such that the extra file is added by next reindex
+	count, err := indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), count)
+
+	delta, err := indexer.Reindex()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delta.Added)
+}
+
+func (suite *FzdTestSuite) TestReindexAndSearchRace() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	suite.indexAndOpen()
+
+	var wg sync.WaitGroup
+	wg.Add(2 * raceTimes)
+	for i := 0; i < raceTimes; i++ {
+		go func() {
+			_, err := indexer.Reindex()
+			assert.NoError(t, err)
+			wg.Done()
+		}()
+
+		go func() {
+			res, err := indexer.Search("txt")
+			assert.NoError(t, err)
+			suite.readSearchResults(res, 3)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func (suite *FzdTestSuite) TestClose() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	name1 := suite.indexAndOpen()
+	name2 := suite.indexAndOpen()
+
+	indexName, err := indexer.IndexName()
+	assert.NoError(t, err)
+	assert.Equal(t, name2, indexName)
+
+	dirnames1 := suite.readIndexesDirnames(3)
+	assert.ElementsMatch(t, []string{
+		fzd.HeadFileName,
+		name1,
+		name2,
+	}, dirnames1)
+
+	err = indexer.Close()
+	assert.NoError(t, err)
+
+	_, err = indexer.IndexName()
+	assert.ErrorIs(t, err, fzd.ErrIndexNotOpened)
+
+	dirnames2 := suite.readIndexesDirnames(2)
+	assert.ElementsMatch(t, []string{
+		fzd.HeadFileName,
+		name2,
+	}, dirnames2, "other indexes should be removed and cleaned up")
+}
+
+func (suite *FzdTestSuite) TestCloseKeepsIndexesInUseByOthers() {
+	t := suite.T()
+	indexer := suite.indexer
+
+	name1 := suite.indexAndOpen()
+
+	// other indexer sharing the same base path, i.e. another process
+	other, err := fzd.NewIndexer(suite.indexesDir, fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}))
+	assert.NoError(t, err)
+	name2, err := other.Index()
+	assert.NoError(t, err)
+	err = other.OpenAndSwap(name2)
 	assert.NoError(t, err)
 
-	err = indexer.OpenAndSwap(name1)
+	err = indexer.Close()
 	assert.NoError(t, err)
+	dirnames1 := suite.readIndexesDirnames(3)
+	assert.ElementsMatch(t, []string{
+		fzd.HeadFileName,
+		name1,
+		name2,
+	}, dirnames1, "indexes should be kept while other indexer is opened")
 
-	headFileIndexName1 := suite.readHeadFile()
-	assert.Equal(t, name1, headFileIndexName1)
+	err = other.Close()
+	assert.NoError(t, err)
+	dirnames2 := suite.readIndexesDirnames(2)
+	assert.ElementsMatch(t, []string{
+		fzd.HeadFileName,
+		name2,
+	}, dirnames2, "unused indexes should be removed by the last one closing")
+}
 
-	headFileModTime1 := suite.headFileModTime()
+func (suite *FzdTestSuite) TestCloseKeepsIndexOfHeadFile() {
+	t := suite.T()
+	indexer := suite.indexer
 
-	dirnames1 := suite.readIndexesDirnames(2)
+	name1 := suite.indexAndOpen()
+
+	// other indexer swapped HEAD file and closed, while this indexer is still opened
+	other, err := fzd.NewIndexer(suite.indexesDir, fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}))
+	assert.NoError(t, err)
+	name2, err := other.Index()
+	assert.NoError(t, err)
+	err = other.OpenAndSwap(name2)
+	assert.NoError(t, err)
+	err = other.Close()
+	assert.NoError(t, err)
+
+	err = indexer.Close()
+	assert.NoError(t, err)
+	dirnames := suite.readIndexesDirnames(3)
 	assert.ElementsMatch(t, []string{
 		fzd.HeadFileName,
-		headFileIndexName1,
-	}, dirnames1)
+		name1,
+		name2,
+	}, dirnames, "index specified by HEAD file should be kept")
+}
 
-	name2, err := indexer.Index()
-	assert.NoError(t, err)
+func (suite *FzdTestSuite) TestCloseKeepsGenerationsWithinRetention() {
+	t := suite.T()
 
-	err = indexer.OpenAndSwap(name1)
+	err := suite.indexer.Close()
+	assert.NoError(t, err)
+	indexer, err := fzd.NewIndexer(suite.indexesDir,
+		fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}),
+		fzd.WithRetention(2),
+	)
 	assert.NoError(t, err)
+	suite.indexer = indexer
 
-	headFileIndexName2 := suite.readHeadFile()
-	assert.Equal(t, name1, headFileIndexName2)
+	suite.indexAndOpen()
+	name2 := suite.indexAndOpen()
+	name3 := suite.indexAndOpen()
 
-	headFileModTime2 := suite.headFileModTime()
-	assert.Equal(t, headFileModTime1, headFileModTime2, fmt.Sprintf("%v file should not be updated if OpenAndSwap with same index", fzd.HeadFileName))
+	err = indexer.Close()
+	assert.NoError(t, err)
 
-	dirnames2 := suite.readIndexesDirnames(3)
+	dirnames := suite.readIndexesDirnames(3)
 	assert.ElementsMatch(t, []string{
 		fzd.HeadFileName,
-		headFileIndexName2,
 		name2,
-	}, dirnames2, "other indexes will not be removed for OpenAndSwap")
-
-	indexName, err := indexer.IndexName()
-	assert.NoError(t, err)
-	assert.Equal(t, name1, indexName)
+		name3,
+	}, dirnames, "only generations within retention should be kept")
 }
 
-func (suite *FzdTestSuite) TestDocCount() {
+func (suite *FzdTestSuite) TestGenerations() {
 	t := suite.T()
 	indexer := suite.indexer
 
-	suite.indexAndOpen()
+	name1 := suite.indexAndOpen()
+	name2 := suite.indexAndOpen()
 
-	count, err := indexer.DocCount()
+	gens, err := indexer.Generations()
 	assert.NoError(t, err)
-	assert.Equal(t, uint64(6), count)
+	if assert.Len(t, gens, 2) {
+		assert.Equal(t, name2, gens[0].Name)
+		assert.True(t, gens[0].Current)
+		assert.Equal(t, uint64(6), gens[0].DocCount)
+		assert.Equal(t, fzd.MappingVersion, gens[0].MappingVersion)
+
+		assert.Equal(t, name1, gens[1].Name)
+		assert.False(t, gens[1].Current)
+		assert.True(t, gens[1].Created.Before(gens[0].Created))
+	}
 }
 
-func (suite *FzdTestSuite) TestDocCountReturnsErrorIfNotOpened() {
+func (suite *FzdTestSuite) TestRollback() {
 	t := suite.T()
 	indexer := suite.indexer
 
-	_, err := indexer.DocCount()
-	assert.ErrorIs(t, err, fzd.ErrIndexNotOpened)
+	name1 := suite.indexAndOpen()
+	name2 := suite.indexAndOpen()
+	name3 := suite.indexAndOpen()
+
+	// rollback to previous generation of the current one
+	name, err := indexer.Rollback("")
+	assert.NoError(t, err)
+	assert.Equal(t, name2, name)
+	assert.Equal(t, name2, suite.readHeadFile())
+
+	name, err = indexer.Rollback("")
+	assert.NoError(t, err)
+	assert.Equal(t, name1, name)
+
+	_, err = indexer.Rollback("")
+	assert.ErrorIs(t, err, fzd.ErrNoPreviousGeneration)
+
+	// roll forward to specified generation
+	name, err = indexer.Rollback(name3)
+	assert.NoError(t, err)
+	assert.Equal(t, name3, name)
+	indexName, err := indexer.IndexName()
+	assert.NoError(t, err)
+	assert.Equal(t, name3, indexName)
+
+	_, err = indexer.Rollback("missing")
+	assert.ErrorIs(t, err, fzd.ErrGenerationDoesNotExist)
 }
 
-func (suite *FzdTestSuite) TestSearch() {
+func (suite *FzdTestSuite) TestRollbackAfterReindexRestoresEarlierDocs() {
 	t := suite.T()
 	indexer := suite.indexer
 
-	suite.indexAndOpen()
+	paths := func() []string {
+		res, err := indexer.SearchWith(bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), 100, 0, false))
+		assert.NoError(t, err)
+		hits := suite.readSearchResults(res, int(res.Total))
+		sort.Strings(hits)
+		return hits
+	}
 
-	res, err := indexer.Search("txt")
+	name1 := suite.indexAndOpen()
+	earlier := paths()
+	name2 := suite.indexAndOpen()
+
+	// reindex updates the opened generation in place, while the previous one is left as is
+	file := filepath.Join(suite.level1Dir, "reindexed.txt")
+	err := os.WriteFile(file, []byte("content"), fileMode)
 	assert.NoError(t, err)
+	defer os.Remove(file)
+	delta, err := indexer.Reindex()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delta.Added)
+	reindexed := paths()
+	assert.Contains(t, reindexed, file)
 
-	hits := suite.readSearchResults(res, 3)
-	assert.Equal(t, []string{
-		suite.level0File,
-		suite.level1File,
-		suite.level2File,
-	}, hits)
+	name, err := indexer.Rollback("")
+	assert.NoError(t, err)
+	assert.Equal(t, name1, name)
+	assert.Equal(t, earlier, paths())
+
+	name, err = indexer.Rollback(name2)
+	assert.NoError(t, err)
+	assert.Equal(t, name2, name)
+	assert.Equal(t, reindexed, paths())
 }
 
-func (suite *FzdTestSuite) TestSearchWith() {
+func (suite *FzdTestSuite) TestRollbackReturnsErrorIfSharedSubIndexReindexed() {
 	t := suite.T()
-	indexer := suite.indexer
 
-	suite.indexAndOpen()
+	other := t.TempDir()
+	err := os.WriteFile(filepath.Join(other, "other.txt"), []byte("content"), fileMode)
+	assert.NoError(t, err)
 
-	req := bleve.NewSearchRequest(bleve.NewWildcardQuery("*txt"))
-	res, err := indexer.SearchWith(req)
+	err = suite.indexer.Close()
 	assert.NoError(t, err)
+	indexer, err := fzd.NewIndexer(suite.indexesDir,
+		fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}),
+		fzd.WithLocation(other, fzd.LocationOption{}),
+		fzd.WithRetention(2),
+	)
+	assert.NoError(t, err)
+	suite.indexer = indexer
 
-	hits := suite.readSearchResults(res, 3)
-	assert.Equal(t, []string{
-		suite.level0File,
-		suite.level1File,
-		suite.level2File,
-	}, hits)
+	name1, err := indexer.Index()
+	assert.NoError(t, err)
+	err = indexer.OpenAndSwap(name1)
+	assert.NoError(t, err)
+	// sub-index of level0Dir is carried over from name1
+	name2, _, err := indexer.IndexContext(context.Background(), fzd.IndexOptions{Locations: []string{other}})
+	assert.NoError(t, err)
+	err = indexer.OpenAndSwap(name2)
+	assert.NoError(t, err)
+
+	file := filepath.Join(suite.level1Dir, "reindexed.txt")
+	err = os.WriteFile(file, []byte("content"), fileMode)
+	assert.NoError(t, err)
+	defer os.Remove(file)
+	_, err = indexer.Reindex()
+	assert.NoError(t, err)
+
+	gens, err := indexer.Generations()
+	assert.NoError(t, err)
+	if assert.Len(t, gens, 2) {
+		assert.Nil(t, gens[0].Modified, "opened generation is not marked")
+		assert.NotNil(t, gens[1].Modified)
+	}
+	_, err = indexer.Rollback("")
+	assert.ErrorIs(t, err, fzd.ErrGenerationModified)
+	indexName, err := indexer.IndexName()
+	assert.NoError(t, err)
+	assert.Equal(t, name2, indexName)
 }
 
-func (suite *FzdTestSuite) TestSearchAndDocCountAfterIndexSwapped() {
+func (suite *FzdTestSuite) TestClean() {
 	t := suite.T()
-	indexer := suite.indexer
 
-	name1 := suite.indexAndOpen()
+	err := suite.indexer.Close()
+	assert.NoError(t, err)
+	indexer, err := fzd.NewIndexer(suite.indexesDir,
+		fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}),
+		fzd.WithRetention(2),
+	)
+	assert.NoError(t, err)
+	suite.indexer = indexer
 
-	indexName1, err := indexer.IndexName()
+	// other indexer sharing the same base path, i.e. another process
+	other, err := fzd.NewIndexer(suite.indexesDir, fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}))
+	assert.NoError(t, err)
+	name1, err := other.Index()
+	assert.NoError(t, err)
+	err = other.OpenAndSwap(name1)
+	assert.NoError(t, err)
+	name2, err := indexer.Index()
+	assert.NoError(t, err)
+	err = indexer.OpenAndSwap(name2)
 	assert.NoError(t, err)
-	assert.Equal(t, name1, indexName1)
 
-	count1, err := indexer.DocCount()
+	// generation opened by others is in use, which could not be cleaned
+	removed, err := indexer.Clean(false)
 	assert.NoError(t, err)
-	assert.Equal(t, uint64(6), count1)
+	assert.Empty(t, removed)
 
-	res1, err := indexer.Search("txt")
+	err = other.Close()
+	assert.NoError(t, err)
+	// generations within retention are kept on close, but cleaned regardless of retention while index is opened
+	removed, err = indexer.Clean(false)
 	assert.NoError(t, err)
+	assert.Equal(t, []string{name1}, removed)
+	assert.ElementsMatch(t, []string{
+		fzd.HeadFileName,
+		name2,
+	}, suite.readIndexesDirnames(2))
 
-	hits1 := suite.readSearchResults(res1, 3)
-	assert.Equal(t, []string{
-		suite.level0File,
-		suite.level1File,
-		suite.level2File,
-	}, hits1)
+	// index opened is in use, which could not be cleaned all together
+	_, err = indexer.Clean(true)
+	assert.ErrorIs(t, err, fzd.ErrIndexLocked)
 
-	time1, err := indexer.LastIndexed()
+	err = indexer.RecordSelection(suite.level0File)
+	assert.NoError(t, err)
+	err = indexer.Close()
 	assert.NoError(t, err)
+	removed, err = indexer.Clean(true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{name2}, removed)
+	assert.ElementsMatch(t, []string{
+		fzd.HistoryFileName,
+	}, suite.readIndexesDirnames(1))
 
-	extraFile := filepath.Join(suite.level0Dir, "extra.txt")
+	err = indexer.Open()
+	assert.ErrorIs(t, err, fzd.ErrIndexHeadDoesNotExist)
+}
+
+func (suite *FzdTestSuite) TestIndexLocationsRebuildsOnlySpecified() {
+	t := suite.T()
+
+	err := suite.indexer.Close()
+	assert.NoError(t, err)
+	indexer, err := fzd.NewIndexer(suite.indexesDir,
+		fzd.WithLocation(suite.level1Dir, fzd.LocationOption{}),
+		fzd.WithLocation(suite.level2Dir, fzd.LocationOption{}),
+	)
+	assert.NoError(t, err)
+	suite.indexer = indexer
+
+	name1 := suite.indexAndOpen()
+	assert.Equal(t, map[string]string{
+		suite.level1Dir: name1,
+		suite.level2Dir: name1,
+	}, suite.readHead().Locations)
+
+	extraFile := filepath.Join(suite.level2Dir, "extra.txt")
 	err = os.WriteFile(extraFile, []byte("content"), fileMode)
 	assert.NoError(t, err)
 	defer os.Remove(extraFile)
 
-	time.Sleep(1 * time.Second)
-
-	name2 := suite.indexAndOpen()
-	assert.NotEqual(t, name2, name1)
+	name2, report, err := indexer.IndexContext(context.Background(), fzd.IndexOptions{Locations: []string{suite.level2Dir}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]fzd.LocationReport{
+		suite.level2Dir: {Paths: 3},
+	}, report.Locations, "only specified location should be walked")
+	err = indexer.OpenAndSwap(name2)
+	assert.NoError(t, err)
 
-	indexName2, err := indexer.IndexName()
+	// sub-index of other location is carried over from previous generation
+	assert.Equal(t, map[string]string{
+		suite.level1Dir: name1,
+		suite.level2Dir: name2,
+	}, suite.readHead().Locations)
+	count, err := indexer.DocCount()
 	assert.NoError(t, err)
-	assert.Equal(t, name2, indexName2)
+	assert.Equal(t, uint64(5), count)
+	res, err := indexer.Search("extra")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{extraFile}, suite.readSearchResults(res, 1))
 
-	count2, err := indexer.DocCount()
+	// previous generation is kept beyond retention, as its sub-index is still in use
+	err = indexer.Close()
 	assert.NoError(t, err)
-	assert.Equal(t, uint64(7), count2)
+	dirnames := suite.readIndexesDirnames(3)
+	assert.Contains(t, dirnames, name1)
+	assert.Contains(t, dirnames, name2)
 
-	res2, err := indexer.Search("txt")
+	err = indexer.Open()
 	assert.NoError(t, err)
+	count, err = indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), count)
+}
 
-	hits2 := suite.readSearchResults(res2, 4)
-	assert.ElementsMatch(t, []string{
-		extraFile,
-		suite.level0File,
-		suite.level1File,
-		suite.level2File,
-	}, hits2)
+func (suite *FzdTestSuite) TestIndexLocationsReturnsErrorIfNotConfigured() {
+	t := suite.T()
 
-	time2, err := indexer.LastIndexed()
-	assert.NoError(t, err)
-	assert.Greater(t, time2.Unix(), time1.Unix())
+	_, _, err := suite.indexer.IndexContext(context.Background(), fzd.IndexOptions{Locations: []string{suite.level1Dir}})
+	assert.ErrorIs(t, err, fzd.ErrLocationNotConfigured)
+	suite.readIndexesDirnames(0)
 }
 
-func (suite *FzdTestSuite) TestSearchReturnsErrorIfNotOpened() {
+func (suite *FzdTestSuite) TestOpenSingleIndexOfOlderVersions() {
 	t := suite.T()
 	indexer := suite.indexer
 
-	_, err := indexer.Search("txt")
-	assert.ErrorIs(t, err, fzd.ErrIndexNotOpened)
+	// single index for all locations of older versions, where HEAD file only contains index name
+	name := uuid.NewString()
+	index, err := bleve.New(filepath.Join(suite.indexesDir, name), bleve.NewIndexMapping())
+	assert.NoError(t, err)
+	err = index.Index(suite.level0File, map[string]interface{}{"path": suite.level0File})
+	assert.NoError(t, err)
+	err = index.Close()
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(suite.indexesDir, fzd.HeadFileName), []byte(name), fileMode)
+	assert.NoError(t, err)
+
+	err = indexer.Open()
+	assert.NoError(t, err)
+	count, err := indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	// single index could only be rebuilt into sub-index of each location
+	_, err = indexer.Reindex()
+	assert.ErrorIs(t, err, fzd.ErrIndexManifestDoesNotExist)
+	_, _, err = indexer.IndexContext(context.Background(), fzd.IndexOptions{Locations: []string{suite.level0Dir}})
+	assert.NoError(t, err)
 }
 
-func (suite *FzdTestSuite) TestClose() {
+func (suite *FzdTestSuite) TestIndexWithAnalyzer() {
 	t := suite.T()
-	indexer := suite.indexer
 
-	name1 := suite.indexAndOpen()
-	name2 := suite.indexAndOpen()
+	file := filepath.Join(suite.level1Dir, "MyComponent.tsx")
+	err := os.WriteFile(file, []byte("content"), fileMode)
+	assert.NoError(t, err)
+	defer os.Remove(file)
 
-	indexName, err := indexer.IndexName()
+	err = suite.indexer.Close()
 	assert.NoError(t, err)
-	assert.Equal(t, name2, indexName)
+	analyzer := fzd.AnalyzerOptions{CamelCase: true, Lowercase: true}
+	indexer, err := fzd.NewIndexer(suite.indexesDir,
+		fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}),
+		fzd.WithAnalyzer(analyzer),
+	)
+	assert.NoError(t, err)
+	suite.indexer = indexer
 
-	dirnames1 := suite.readIndexesDirnames(3)
-	assert.ElementsMatch(t, []string{
-		fzd.HeadFileName,
-		name1,
-		name2,
-	}, dirnames1)
+	suite.indexAndOpen()
+	assert.Equal(t, analyzer, suite.readHead().Analyzer)
+	for _, term := range []string{"component", "Component", "myComponent"} {
+		res, err := indexer.Search(term)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, res.Hits, term) {
+			assert.Equal(t, file, res.Hits[0].ID, term)
+		}
+	}
 
+	// index should be rebuilt once analyzer is changed
 	err = indexer.Close()
 	assert.NoError(t, err)
+	indexer, err = fzd.NewIndexer(suite.indexesDir, fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}))
+	assert.NoError(t, err)
+	suite.indexer = indexer
+	err = indexer.Open()
+	assert.ErrorIs(t, err, fzd.ErrIndexIncompatible)
+}
 
-	_, err = indexer.IndexName()
-	assert.ErrorIs(t, err, fzd.ErrIndexNotOpened)
+func (suite *FzdTestSuite) TestSearchWithHighlightOfFoldedPath() {
+	t := suite.T()
 
-	dirnames2 := suite.readIndexesDirnames(2)
-	assert.ElementsMatch(t, []string{
-		fzd.HeadFileName,
-		name2,
-	}, dirnames2, "other indexes should be removed and cleaned up")
+	file := filepath.Join(suite.level1Dir, "café-résumé.txt")
+	err := os.WriteFile(file, []byte("content"), fileMode)
+	assert.NoError(t, err)
+	defer os.Remove(file)
+
+	err = suite.indexer.Close()
+	assert.NoError(t, err)
+	indexer, err := fzd.NewIndexer(suite.indexesDir,
+		fzd.WithLocation(suite.level0Dir, fzd.LocationOption{}),
+		fzd.WithAnalyzer(fzd.AnalyzerOptions{ASCIIFolding: true, Lowercase: true}),
+	)
+	assert.NoError(t, err)
+	suite.indexer = indexer
+
+	suite.indexAndOpen()
+	opts := fzd.DefaultSearchOptions()
+	opts.Highlight = true
+	res, err := indexer.SearchWithOptions("resume", opts)
+	assert.NoError(t, err)
+
+	hits := fzd.NewHits(res)
+	if assert.NotEmpty(t, hits) {
+		assert.Equal(t, file, hits[0].Path)
+		assert.Contains(t, hits[0].Fragments[fzd.FieldPath][0], "café-<mark>résumé</mark>.txt")
+		assert.NotEmpty(t, hits[0].Matches)
+		for _, m := range hits[0].Matches {
+			assert.Equal(t, "résumé", hits[0].Path[m.Start:m.End])
+		}
+	}
+}
+
+func (suite *FzdTestSuite) TestNewIndexerReturnsErrorIfAnalyzerInvalid() {
+	t := suite.T()
+
+	_, err := fzd.NewIndexer(suite.indexesDir, fzd.WithAnalyzer(fzd.AnalyzerOptions{Tokenizer: "["}))
+	assert.Error(t, err)
 }
 
 func (suite *FzdTestSuite) TestCloseNoErrorIfNotOpened() {
@@ -565,20 +1657,32 @@ func (suite *FzdTestSuite) readIndexesDirnames(expectedLen int) []string {
 
 	entries, err := os.ReadDir(suite.indexesDir)
 	assert.NoError(t, err)
-	assert.Equal(t, expectedLen, len(entries))
 
 	var names []string
 	for _, e := range entries {
+		// lock file is kept within base path once created
+		if e.Name() == fzd.LockFileName {
+			continue
+		}
 		names = append(names, e.Name())
 	}
+	assert.Equal(t, expectedLen, len(names))
 	return names
 }
 
+// readHeadFile returns index name specified by HEAD file
 func (suite *FzdTestSuite) readHeadFile() string {
+	return suite.readHead().Name
+}
+
+func (suite *FzdTestSuite) readHead() fzd.Head {
 	path := filepath.Join(suite.indexesDir, fzd.HeadFileName)
 	content, err := os.ReadFile(path)
 	assert.NoError(suite.T(), err)
-	return string(content)
+	var head fzd.Head
+	err = json.Unmarshal(content, &head)
+	assert.NoError(suite.T(), err)
+	return head
 }
 
 func (suite *FzdTestSuite) headFileModTime() time.Time {
@@ -0,0 +1,311 @@
+package fzd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// GenerationFileName is name of file within each index directory, which is manifest of the index generation
+const GenerationFileName = "GENERATION"
+
+// DefaultRetention is default number of index generations kept, including the current one
+const DefaultRetention = 1
+
+var (
+	// Error where specified generation does not exist within base path
+	ErrGenerationDoesNotExist = errors.New("index generation does not exist")
+
+	// Error where there is no generation older than the current one to rollback to
+	ErrNoPreviousGeneration = errors.New("no previous index generation to rollback to")
+
+	// Error where sub-indexes of generation to rollback to are modified by reindex after it is created
+	ErrGenerationModified = errors.New("index generation is modified after it is created")
+)
+
+// Generation of index kept within base path, which could be rolled back to
+type Generation struct {
+	Head
+
+	// Current is true if the generation is specified by HEAD file
+	Current bool `json:"current"`
+}
+
+// WithRetention sets number of index generations kept on Close, including the current one
+// Older generations are removed, while retained generations could be rolled back to with Rollback
+func WithRetention(retention int) IndexerOption {
+	return func(i *Indexer) {
+		i.retention = retention
+	}
+}
+
+// Generations returns index generations within base path, sorted from the newest created
+// Indexes built before generations are recorded are listed with modification time of their directories
+func (i *Indexer) Generations() ([]Generation, error) {
+	err := i.lock.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer i.lock.release()
+
+	return readGenerations(i.basePath)
+}
+
+// Rollback swaps to specified index generation with OpenAndSwap, and returns name of the generation
+// If name is empty, it rolls back to the newest generation created before the current one
+// Generation sharing sub-indexes reindexed in place afterwards could not be rolled back to, where ErrGenerationModified is returned
+func (i *Indexer) Rollback(name string) (string, error) {
+	gens, err := i.Generations()
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		name, err = previousGeneration(gens)
+		if err != nil {
+			return "", err
+		}
+	}
+	g, ok := findGeneration(gens, name)
+	if !ok {
+		return "", fmt.Errorf("%w: %v", ErrGenerationDoesNotExist, name)
+	}
+	if g.Modified != nil && !g.Current {
+		return "", fmt.Errorf("%w: %v is reindexed in place at %v, rebuild it with index instead",
+			ErrGenerationModified, name, g.Modified.Format(time.RFC3339))
+	}
+	err = i.OpenAndSwap(name)
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Clean removes index generations other than the current one and those in use by others, regardless of retention,
+// and returns names of removed ones
+// If all is true, HEAD file is removed along with all generations, such that index has to be created again,
+// where ErrIndexLocked is returned if any generation is in use, including opened by this Indexer
+// History of selections is kept
+func (i *Indexer) Clean(all bool) ([]string, error) {
+	var removed []string
+	err := i.lock.exclusive(func() error {
+		var keep []string
+		if all {
+			entries, err := os.ReadDir(i.basePath)
+			if err != nil {
+				return fmt.Errorf("failed to read %v: %w", i.basePath, err)
+			}
+			for _, e := range entries {
+				if !e.IsDir() {
+					continue
+				}
+				inUse, err := generationInUse(filepath.Join(i.basePath, e.Name()))
+				if err != nil {
+					return err
+				}
+				if inUse {
+					return fmt.Errorf("%w: index is in use and could not be cleaned", ErrIndexLocked)
+				}
+			}
+		} else {
+			// index specified by corrupted HEAD file is unknown, which could only be cleaned all together
+			head, err := readHead(i.basePath)
+			if err != nil && !errors.Is(err, ErrIndexHeadDoesNotExist) {
+				return err
+			}
+			gens, err := readGenerations(i.basePath)
+			if err != nil {
+				return err
+			}
+			keep = referencedGenerations(gens, []string{head.Name})
+		}
+		var err error
+		removed, err = removeIndexesExclude(i.basePath, keep...)
+		if err != nil {
+			return err
+		}
+		if all {
+			err = os.Remove(filepath.Join(i.basePath, HeadFileName))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove %v: %w", HeadFileName, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+func readGenerations(basePath string) ([]Generation, error) {
+	head, err := readHead(basePath)
+	if err != nil && !errors.Is(err, ErrIndexHeadDoesNotExist) {
+		return nil, err
+	}
+	entries, err := os.ReadDir(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", basePath, err)
+	}
+
+	var gens []Generation
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		g, err := readGeneration(basePath, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		gens = append(gens, Generation{
+			Head:    g,
+			Current: e.Name() == head.Name,
+		})
+	}
+	sort.SliceStable(gens, func(i, j int) bool {
+		return gens[i].Created.After(gens[j].Created)
+	})
+	return gens, nil
+}
+
+// readGeneration reads manifest of index generation, which falls back to modification time of the index directory
+func readGeneration(basePath string, name string) (Head, error) {
+	path := filepath.Join(basePath, name, GenerationFileName)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		info, err := os.Stat(filepath.Dir(path))
+		if err != nil {
+			return Head{}, fmt.Errorf("failed to read %v: %w", filepath.Dir(path), err)
+		}
+		return Head{Name: name, Created: info.ModTime()}, nil
+	}
+	if err != nil {
+		return Head{}, fmt.Errorf("failed to read %v: %w", path, err)
+	}
+	var g Head
+	err = json.Unmarshal(content, &g)
+	if err != nil {
+		return Head{}, fmt.Errorf("failed to parse %v: %w", path, err)
+	}
+	g.Name = name
+	return g, nil
+}
+
+func writeGeneration(basePath string, g Head) error {
+	path := filepath.Join(basePath, g.Name, GenerationFileName)
+	content, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %v: %w", path, err)
+	}
+	err = writeFileAtomic(path, content)
+	if err != nil {
+		return fmt.Errorf("failed to write %v: %w", path, err)
+	}
+	return nil
+}
+
+// previousGeneration returns the newest generation created before the current one
+func previousGeneration(gens []Generation) (string, error) {
+	var current *Generation
+	for n := range gens {
+		if gens[n].Current {
+			current = &gens[n]
+			break
+		}
+	}
+	for _, g := range gens {
+		if g.Current {
+			continue
+		}
+		if current == nil || g.Created.Before(current.Created) {
+			return g.Name, nil
+		}
+	}
+	return "", ErrNoPreviousGeneration
+}
+
+func findGeneration(gens []Generation, name string) (Generation, bool) {
+	for _, g := range gens {
+		if g.Name == name {
+			return g, true
+		}
+	}
+	return Generation{}, false
+}
+
+// markModified generations other than the opened one, which share sub-indexes of specified generations to be updated in place
+// Generations being built are not marked, as their manifests are written after sub-indexes are carried over
+func (i *Indexer) markModified(opened string, generations []string) error {
+	return i.lock.exclusive(func() error {
+		gens, err := readGenerations(i.basePath)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, g := range gens {
+			if g.Name == opened || g.Modified != nil || g.Locations == nil {
+				continue
+			}
+			for _, name := range g.Locations {
+				if !contains(generations, name) {
+					continue
+				}
+				g.Head.Modified = &now
+				err = writeGeneration(i.basePath, g.Head)
+				if err != nil {
+					return err
+				}
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// retainedGenerations returns names of generations to be kept, which are the current one and the newest others
+func retainedGenerations(gens []Generation, retention int) []string {
+	var names []string
+	others := 0
+	for _, g := range gens {
+		if g.Current {
+			names = append(names, g.Name)
+			continue
+		}
+		if others < retention-1 {
+			names = append(names, g.Name)
+			others++
+		}
+	}
+	return names
+}
+
+// referencedGenerations returns specified generations along with generations containing their sub-indexes
+func referencedGenerations(gens []Generation, names []string) []string {
+	referenced := append([]string(nil), names...)
+	for _, g := range gens {
+		if !contains(names, g.Name) {
+			continue
+		}
+		for _, name := range g.Locations {
+			referenced = append(referenced, name)
+		}
+	}
+	return referenced
+}
+
+// newGeneration for newly built index with current version and mapping, along with generations of each location
+func newGeneration(name string, locations map[string]LocationOption, generations map[string]string, analyzer AnalyzerOptions, docCount uint64) Head {
+	return Head{
+		Name:           name,
+		Created:        time.Now(),
+		Version:        Version,
+		MappingVersion: MappingVersion,
+		LocationsHash:  locationsHash(locations),
+		DocCount:       docCount,
+		Locations:      generations,
+		Analyzer:       analyzer,
+	}
+}
@@ -0,0 +1,87 @@
+package fzd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndReadGeneration(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Mkdir(filepath.Join(dir, "index"), 0700)
+	assert.NoError(t, err)
+
+	g := newGeneration("index", map[string]LocationOption{"/a": {}}, map[string]string{"/a": "index"}, AnalyzerOptions{Lowercase: true}, 10)
+	g.Created = g.Created.Round(0)
+	err = writeGeneration(dir, g)
+	assert.NoError(t, err)
+
+	actual, err := readGeneration(dir, "index")
+	assert.NoError(t, err)
+	assert.True(t, g.Created.Equal(actual.Created))
+	actual.Created = g.Created
+	assert.Equal(t, g, actual)
+}
+
+func TestReadGenerationWithoutManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index")
+	err := os.Mkdir(path, 0700)
+	assert.NoError(t, err)
+	modTime := time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)
+	err = os.Chtimes(path, modTime, modTime)
+	assert.NoError(t, err)
+
+	g, err := readGeneration(dir, "index")
+	assert.NoError(t, err)
+	assert.Equal(t, "index", g.Name)
+	assert.True(t, modTime.Equal(g.Created))
+	assert.Equal(t, 0, g.MappingVersion)
+}
+
+func TestPreviousGeneration(t *testing.T) {
+	now := time.Now()
+	gens := []Generation{
+		{Head: Head{Name: "newer", Created: now}},
+		{Head: Head{Name: "current", Created: now.Add(-time.Hour)}, Current: true},
+		{Head: Head{Name: "previous", Created: now.Add(-2 * time.Hour)}},
+		{Head: Head{Name: "oldest", Created: now.Add(-3 * time.Hour)}},
+	}
+	name, err := previousGeneration(gens)
+	assert.NoError(t, err)
+	assert.Equal(t, "previous", name)
+
+	_, err = previousGeneration(gens[:2])
+	assert.ErrorIs(t, err, ErrNoPreviousGeneration)
+
+	// newest generation is rolled back to if there is no current one
+	name, err = previousGeneration([]Generation{gens[0], gens[2]})
+	assert.NoError(t, err)
+	assert.Equal(t, "newer", name)
+}
+
+func TestRetainedGenerations(t *testing.T) {
+	gens := []Generation{
+		{Head: Head{Name: "newer"}},
+		{Head: Head{Name: "current"}, Current: true},
+		{Head: Head{Name: "previous"}},
+		{Head: Head{Name: "oldest"}},
+	}
+	assert.Equal(t, []string{"current"}, retainedGenerations(gens, 1))
+	assert.Equal(t, []string{"newer", "current", "previous"}, retainedGenerations(gens, 3))
+	assert.Equal(t, []string{"newer", "current", "previous", "oldest"}, retainedGenerations(gens, 10))
+	assert.Equal(t, []string{"current"}, retainedGenerations(gens, 0))
+}
+
+func TestReferencedGenerations(t *testing.T) {
+	gens := []Generation{
+		{Head: Head{Name: "c", Locations: map[string]string{"/a": "a", "/b": "c"}}},
+		{Head: Head{Name: "b", Locations: map[string]string{"/a": "b", "/b": "b"}}},
+		{Head: Head{Name: "a", Locations: map[string]string{"/a": "a", "/b": "a"}}},
+	}
+	referenced := referencedGenerations(gens, []string{"c"})
+	assert.ElementsMatch(t, []string{"c", "a", "c"}, referenced)
+}
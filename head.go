@@ -1,34 +1,186 @@
 package fzd
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 const (
 	HeadFileName = "HEAD"
 )
 
-func writeHead(basePath string, name string) error {
+// Head is manifest of HEAD file, which specifies the current index
+// HEAD file of older versions only contains the index name, where other fields are left empty
+type Head struct {
+	// Name of the current index, which is the directory name within base path
+	Name string `json:"name"`
+
+	// Created is time when the index is built, which is kept as is when it is swapped back by rollback
+	Created time.Time `json:"created"`
+
+	// Version of fzd built the index
+	Version string `json:"version"`
+
+	// MappingVersion of the index, which is 0 for HEAD file of older versions
+	MappingVersion int `json:"mappingVersion"`
+
+	// LocationsHash is hash of locations and their options when the index is built
+	LocationsHash string `json:"locationsHash"`
+
+	// DocCount is number of documents when the index is built, which could be changed by reindex afterwards
+	DocCount uint64 `json:"docCount"`
+
+	// Locations maps each location to generation containing its sub-index, as locations could be indexed separately
+	// It is empty for single index of older versions, where all locations are indexed within the index of Name
+	Locations map[string]string `json:"locations,omitempty"`
+
+	// Analyzer of the index, which is zero value for index of older versions
+	Analyzer AnalyzerOptions `json:"analyzer"`
+
+	// Modified is time when sub-indexes of the generation are updated in place by reindex while another generation is opened,
+	// such that it no longer has the state when it is created and could not be rolled back to
+	Modified *time.Time `json:"modified,omitempty"`
+}
+
+// validate HEAD file content, such that corrupted HEAD file is not used to open index
+func (h Head) validate() error {
+	if h.Name == "" || h.Name == "." || h.Name == ".." || filepath.Base(h.Name) != h.Name {
+		return fmt.Errorf("%w: invalid index name %q", ErrIndexHeadCorrupted, h.Name)
+	}
+	return nil
+}
+
+// compatible checks if the index could be opened with current mapping and analyzer, where HEAD file of older versions
+// without mapping version is assumed to be compatible, as its single index is still opened until it is rebuilt
+func (h Head) compatible(analyzer AnalyzerOptions) error {
+	if h.MappingVersion != 0 && h.MappingVersion != MappingVersion {
+		return fmt.Errorf("%w: index %v has mapping version %v, while %v is required", ErrIndexIncompatible, h.Name, h.MappingVersion, MappingVersion)
+	}
+	if !h.Analyzer.equal(analyzer) {
+		return fmt.Errorf("%w: index %v is analyzed with different options", ErrIndexIncompatible, h.Name)
+	}
+	return nil
+}
+
+// locationsHash of locations and their options, which changes if locations are configured differently
+func locationsHash(locations map[string]LocationOption) string {
+	paths := make([]string, 0, len(locations))
+	for path := range locations {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		// options are printed with sorted map keys, such that the hash is deterministic
+		fmt.Fprintf(h, "%q %v\n", path, locations[path])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeHead writes HEAD file atomically with temp file and rename, such that it is never left partially written
+func writeHead(basePath string, head Head) error {
 	path := filepath.Join(basePath, HeadFileName)
-	err := os.WriteFile(path, []byte(name), 0600)
+	content, err := json.MarshalIndent(head, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %v: %w", path, err)
+	}
+	err = writeFileAtomic(path, content)
 	if err != nil {
 		return fmt.Errorf("failed to write %v: %w", path, err)
 	}
 	return nil
 }
 
-func readHead(basePath string) (string, error) {
+func readHead(basePath string) (Head, error) {
 	path := filepath.Join(basePath, HeadFileName)
 	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
-		return "", ErrIndexHeadDoesNotExist
+		return Head{}, ErrIndexHeadDoesNotExist
 	}
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to read %v: %w", path, err)
+		return Head{}, fmt.Errorf("failed to read %v: %w", path, err)
+	}
+	head, err := parseHead(content)
+	if err != nil {
+		return Head{}, err
+	}
+	return head, nil
+}
+
+// parseHead parses HEAD file content, which is either manifest in JSON or plain index name of older versions
+func parseHead(content []byte) (Head, error) {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return Head{}, fmt.Errorf("%w: file is empty", ErrIndexHeadCorrupted)
+	}
+	if content[0] != '{' {
+		// index names of older versions are always UUIDs, anything else is garbage
+		name := string(content)
+		if _, err := uuid.Parse(name); err != nil {
+			return Head{}, fmt.Errorf("%w: invalid index name %q", ErrIndexHeadCorrupted, name)
+		}
+		return Head{Name: name}, nil
+	}
+
+	var head Head
+	err := json.Unmarshal(content, &head)
+	if err != nil {
+		return Head{}, fmt.Errorf("%w: %v", ErrIndexHeadCorrupted, err)
+	}
+	return head, head.validate()
+}
+
+// writeFileAtomic writes content to temp file within the same directory, then renames it to path after synced
+func writeFileAtomic(path string, content []byte) (err error) {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	_, err = f.Write(content)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir persists rename within directory, it is best effort as directories could not be synced on some platforms
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
 	}
-	name := string(content)
-	return name, nil
+	defer d.Close()
+	d.Sync()
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -13,8 +14,15 @@ func TestWriteAndReadHead(t *testing.T) {
 	assert.NoError(t, err)
 	defer os.RemoveAll(dir)
 
-	indexName := "some index"
-	err = writeHead(dir, indexName)
+	head := Head{
+		Name:           "some index",
+		Created:        time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+		Version:        "v1.0.0",
+		MappingVersion: MappingVersion,
+		LocationsHash:  "hash",
+		DocCount:       10,
+	}
+	err = writeHead(dir, head)
 	assert.NoError(t, err)
 
 	entries, err := os.ReadDir(dir)
@@ -22,9 +30,29 @@ func TestWriteAndReadHead(t *testing.T) {
 	assert.Equal(t, 1, len(entries))
 	assert.Equal(t, HeadFileName, entries[0].Name())
 
-	name, err := readHead(dir)
+	actual, err := readHead(dir)
 	assert.NoError(t, err)
-	assert.Equal(t, indexName, name)
+	assert.Equal(t, head, actual)
+}
+
+func TestWriteHeadOverwritesExisting(t *testing.T) {
+	dir, err := os.MkdirTemp("", "testHead")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = writeHead(dir, Head{Name: "index1"})
+	assert.NoError(t, err)
+	err = writeHead(dir, Head{Name: "index2"})
+	assert.NoError(t, err)
+
+	head, err := readHead(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "index2", head.Name)
+
+	// temp files are renamed, so that only HEAD file is left
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
 }
 
 func TestWriteAndReadHeadForInvalidLocation(t *testing.T) {
@@ -37,6 +65,66 @@ func TestWriteAndReadHeadForInvalidLocation(t *testing.T) {
 	_, err = readHead(path)
 	assert.ErrorIs(t, err, ErrIndexHeadDoesNotExist)
 
-	err = writeHead(path, "test")
+	err = writeHead(path, Head{Name: "test"})
 	assert.Error(t, err)
 }
+
+func TestReadHeadOfOlderVersion(t *testing.T) {
+	dir, err := os.MkdirTemp("", "testHead")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	name := "0b5d3b8e-0b8a-4c5e-9b6a-3c1f1e0a9d2f"
+	err = os.WriteFile(filepath.Join(dir, HeadFileName), []byte(name), 0600)
+	assert.NoError(t, err)
+
+	head, err := readHead(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, Head{Name: name}, head)
+	assert.NoError(t, head.compatible(AnalyzerOptions{}))
+}
+
+func TestReadHeadCorrupted(t *testing.T) {
+	tests := map[string]string{
+		"empty":          "",
+		"truncated":      `{"name": "0b5d3b8e-0b8a-4c5e-9b6a`,
+		"garbage":        "\x00\x00\x00",
+		"no name":        `{"mappingVersion": 1}`,
+		"path traversal": `{"name": "../index"}`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := os.WriteFile(filepath.Join(dir, HeadFileName), []byte(content), 0600)
+			assert.NoError(t, err)
+
+			_, err = readHead(dir)
+			assert.ErrorIs(t, err, ErrIndexHeadCorrupted)
+		})
+	}
+}
+
+func TestHeadCompatible(t *testing.T) {
+	assert.NoError(t, Head{Name: "index", MappingVersion: MappingVersion}.compatible(AnalyzerOptions{}))
+	assert.ErrorIs(t, Head{Name: "index", MappingVersion: MappingVersion + 1}.compatible(AnalyzerOptions{}), ErrIndexIncompatible)
+
+	// analyzer of older versions is the default one
+	assert.NoError(t, Head{Name: "index"}.compatible(AnalyzerOptions{Tokenizer: DefaultTokenizer}))
+	assert.ErrorIs(t, Head{Name: "index", MappingVersion: MappingVersion}.compatible(AnalyzerOptions{Lowercase: true}), ErrIndexIncompatible)
+}
+
+func TestLocationsHash(t *testing.T) {
+	locations := map[string]LocationOption{
+		"/a": {Filters: []Filter{Top}},
+		"/b": {Ignores: []interface{}{"*.log"}},
+	}
+	hash := locationsHash(locations)
+	assert.Equal(t, hash, locationsHash(map[string]LocationOption{
+		"/b": {Ignores: []interface{}{"*.log"}},
+		"/a": {Filters: []Filter{Top}},
+	}))
+	assert.NotEqual(t, hash, locationsHash(map[string]LocationOption{
+		"/a": {Filters: []Filter{Dir}},
+		"/b": {Ignores: []interface{}{"*.log"}},
+	}))
+}
@@ -0,0 +1,199 @@
+package fzd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+const (
+	// HistoryFileName is the file storing selected paths within index base path
+	HistoryFileName = "history.json"
+
+	// DefaultFrecencyWeight is default weight of frecency score blended with text score of hits
+	DefaultFrecencyWeight = 0.3
+
+	// frecencyHalfLife is duration for frecency score to be decayed by half
+	frecencyHalfLife = 7 * 24 * time.Hour
+)
+
+// historyEntry of selected path, where score is accumulated with decay on each selection
+type historyEntry struct {
+	Score    float64   `json:"score"`
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// frecency is frequency score decayed by recency of last selection
+func (e historyEntry) frecency(now time.Time) float64 {
+	return e.Score * decay(now.Sub(e.LastUsed))
+}
+
+func decay(age time.Duration) float64 {
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(frecencyHalfLife))
+}
+
+// history of selected paths, which is stored as file and loaded lazily
+// It is reloaded once the file is modified, as selections could be recorded by other processes
+// File is updated with exclusive lock of base path held, such that selections recorded by others at the same time are not lost
+type history struct {
+	path    string
+	lock    *baseLock
+	mutex   sync.Mutex
+	entries map[string]historyEntry
+	modTime time.Time
+}
+
+func newHistory(basePath string, lock *baseLock) *history {
+	return &history{
+		path: filepath.Join(basePath, HistoryFileName),
+		lock: lock,
+	}
+}
+
+// Caller of load should acquire lock of mutex to be concurrent-safe
+func (h *history) load() error {
+	var modTime time.Time
+	info, err := os.Stat(h.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %v: %w", h.path, err)
+	}
+	if err == nil {
+		modTime = info.ModTime()
+	}
+	if h.entries != nil && modTime.Equal(h.modTime) {
+		return nil
+	}
+
+	entries := make(map[string]historyEntry)
+	content, err := os.ReadFile(h.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %v: %w", h.path, err)
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &entries)
+		if err != nil {
+			return fmt.Errorf("failed to parse %v: %w", h.path, err)
+		}
+	}
+	h.entries = entries
+	h.modTime = modTime
+	return nil
+}
+
+// Caller of save should acquire lock of mutex and exclusive lock of base path to be concurrent-safe
+// It is written atomically, such that history will not be truncated if failed halfway
+func (h *history) save() error {
+	content, err := json.Marshal(h.entries)
+	if err != nil {
+		return fmt.Errorf("failed to serialize history: %w", err)
+	}
+	err = writeFileAtomic(h.path, content)
+	if err != nil {
+		return fmt.Errorf("failed to write %v: %w", h.path, err)
+	}
+	return nil
+}
+
+// update reloads history and saves it if modified by fn, with exclusive lock of base path held across them
+func (h *history) update(fn func() bool) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.lock.exclusive(func() error {
+		err := h.load()
+		if err != nil {
+			return err
+		}
+		if !fn() {
+			return nil
+		}
+		return h.save()
+	})
+}
+
+func (h *history) record(path string, now time.Time) error {
+	return h.update(func() bool {
+		e := h.entries[path]
+		h.entries[path] = historyEntry{
+			Score:    e.frecency(now) + 1,
+			Count:    e.Count + 1,
+			LastUsed: now,
+		}
+		return true
+	})
+}
+
+// prune removes entries of paths that no longer exist, and returns number of removed entries
+func (h *history) prune() (int, error) {
+	removed := 0
+	err := h.update(func() bool {
+		for path := range h.entries {
+			if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+				delete(h.entries, path)
+				removed++
+			}
+		}
+		return removed != 0
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+func (h *history) frecencies(now time.Time) (map[string]float64, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	err := h.load()
+	if err != nil {
+		return nil, err
+	}
+	frecencies := make(map[string]float64, len(h.entries))
+	for path, e := range h.entries {
+		frecencies[path] = e.frecency(now)
+	}
+	return frecencies, nil
+}
+
+// blendFrecency reranks hits with weighted sum of normalized text score and normalized frecency score
+// Hits are then sliced with offset and limit, as more hits are requested for reranking
+func blendFrecency(res *bleve.SearchResult, frecencies map[string]float64, weight float64, offset int, limit int) {
+	var maxFrecency float64
+	for _, h := range res.Hits {
+		if f := frecencies[h.ID]; f > maxFrecency {
+			maxFrecency = f
+		}
+	}
+
+	if maxFrecency > 0 && res.MaxScore > 0 {
+		for _, h := range res.Hits {
+			h.Score = (1-weight)*h.Score/res.MaxScore + weight*frecencies[h.ID]/maxFrecency
+		}
+		sortHits(res.Hits)
+		res.MaxScore = 0
+		for _, h := range res.Hits {
+			res.MaxScore = math.Max(res.MaxScore, h.Score)
+		}
+	}
+
+	if offset > len(res.Hits) {
+		offset = len(res.Hits)
+	}
+	end := offset + limit
+	if end > len(res.Hits) {
+		end = len(res.Hits)
+	}
+	res.Hits = res.Hits[offset:end]
+}
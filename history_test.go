@@ -0,0 +1,161 @@
+package fzd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecay(t *testing.T) {
+	assert.Equal(t, 1.0, decay(0))
+	assert.Equal(t, 1.0, decay(-time.Hour))
+	assert.InDelta(t, 0.5, decay(frecencyHalfLife), 1e-9)
+	assert.InDelta(t, 0.25, decay(2*frecencyHalfLife), 1e-9)
+}
+
+func TestHistoryRecordAccumulatesDecayedScore(t *testing.T) {
+	dir, err := os.MkdirTemp("", "testHistory")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	h := newHistory(dir, newBaseLock(dir, 0))
+	now := time.Now()
+	path := filepath.Join(dir, "selected")
+
+	err = h.record(path, now)
+	assert.NoError(t, err)
+	err = h.record(path, now.Add(frecencyHalfLife))
+	assert.NoError(t, err)
+
+	frecencies, err := h.frecencies(now.Add(frecencyHalfLife))
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.5, frecencies[path], 1e-9)
+
+	// reload from file
+	loaded := newHistory(dir, newBaseLock(dir, 0))
+	frecencies, err = loaded.frecencies(now.Add(2 * frecencyHalfLife))
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.75, frecencies[path], 1e-9)
+	assert.Equal(t, 2, loaded.entries[path].Count)
+}
+
+func TestHistoryPrune(t *testing.T) {
+	dir, err := os.MkdirTemp("", "testHistory")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "existing")
+	err = os.WriteFile(existing, []byte("content"), fileMode)
+	assert.NoError(t, err)
+	deleted := filepath.Join(dir, "deleted")
+
+	h := newHistory(dir, newBaseLock(dir, 0))
+	now := time.Now()
+	assert.NoError(t, h.record(existing, now))
+	assert.NoError(t, h.record(deleted, now))
+
+	removed, err := h.prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	frecencies, err := newHistory(dir, newBaseLock(dir, 0)).frecencies(now)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{existing: 1}, frecencies)
+}
+
+func TestHistoryReloadsIfModified(t *testing.T) {
+	dir, err := os.MkdirTemp("", "testHistory")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	h := newHistory(dir, newBaseLock(dir, 0))
+	now := time.Now()
+	frecencies, err := h.frecencies(now)
+	assert.NoError(t, err)
+	assert.Empty(t, frecencies)
+
+	// selection recorded by other process
+	path := filepath.Join(dir, "selected")
+	assert.NoError(t, newHistory(dir, newBaseLock(dir, 0)).record(path, now))
+
+	frecencies, err = h.frecencies(now)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{path: 1}, frecencies)
+}
+
+func TestHistoryRecordConcurrentlyByOthers(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// each history is of different process, where selections of all of them are kept
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			h := newHistory(dir, newBaseLock(dir, DefaultLockTimeout))
+			assert.NoError(t, h.record(filepath.Join(dir, strconv.Itoa(i)), now))
+		}(i)
+	}
+	wg.Wait()
+
+	frecencies, err := newHistory(dir, newBaseLock(dir, 0)).frecencies(now)
+	assert.NoError(t, err)
+	assert.Len(t, frecencies, 10)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	for _, e := range entries {
+		assert.Contains(t, []string{HistoryFileName, LockFileName}, e.Name(), "temp files should not be left behind")
+	}
+}
+
+func TestHistoryFrecenciesIfNotExist(t *testing.T) {
+	dir := filepath.Join(os.TempDir(), "testHistoryNotExist")
+	h := newHistory(dir, newBaseLock(dir, 0))
+	frecencies, err := h.frecencies(time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, frecencies)
+}
+
+func newSearchResult(scores map[string]float64, ids ...string) *bleve.SearchResult {
+	res := &bleve.SearchResult{}
+	for _, id := range ids {
+		res.Hits = append(res.Hits, &search.DocumentMatch{ID: id, Score: scores[id]})
+		if scores[id] > res.MaxScore {
+			res.MaxScore = scores[id]
+		}
+	}
+	return res
+}
+
+func TestBlendFrecency(t *testing.T) {
+	res := newSearchResult(map[string]float64{"a": 4, "b": 3, "c": 1}, "a", "b", "c")
+	blendFrecency(res, map[string]float64{"c": 2}, 0.5, 0, 2)
+
+	var ids []string
+	for _, h := range res.Hits {
+		ids = append(ids, h.ID)
+	}
+	assert.Equal(t, []string{"c", "a"}, ids)
+	assert.InDelta(t, 0.625, res.MaxScore, 1e-9)
+}
+
+func TestBlendFrecencyWithoutHistoryOnlySlices(t *testing.T) {
+	res := newSearchResult(map[string]float64{"a": 4, "b": 3, "c": 1}, "a", "b", "c")
+	blendFrecency(res, nil, 0.5, 1, 5)
+
+	var ids []string
+	for _, h := range res.Hits {
+		ids = append(ids, h.ID)
+	}
+	assert.Equal(t, []string{"b", "c"}, ids)
+	assert.Equal(t, 3.0, res.Hits[0].Score)
+}
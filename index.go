@@ -1,13 +1,14 @@
 package fzd
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 
 	"github.com/blevesearch/bleve/v2"
-	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
-	"github.com/blevesearch/bleve/v2/analysis/tokenizer/regexp"
 	"github.com/blevesearch/bleve/v2/mapping"
 	"github.com/google/uuid"
 	"github.com/horacehylee/fzd/walker"
@@ -17,115 +18,275 @@ func newIndexName() string {
 	return uuid.NewString()
 }
 
-func newIndexMapping() (*mapping.IndexMappingImpl, error) {
-	mapping := bleve.NewIndexMapping()
+// MappingVersion of index mapping, which should be bumped on changes of mapping incompatible with existing indexes
+const MappingVersion = 2
 
-	// TODO: may have config for custom tokenizer regexp (not limiting for undescore one)
-	customTokenizerName := "custom_tokenizer"
-	customAnalyzerName := "custom_analyzer"
-	err := mapping.AddCustomTokenizer(customTokenizerName,
-		map[string]interface{}{
-			"type":   regexp.Name,
-			"regexp": `[^\W_]+`,
-		},
-	)
+func newIndexMapping(analyzer AnalyzerOptions) (*mapping.IndexMappingImpl, error) {
+	mapping := bleve.NewIndexMapping()
+	name, err := analyzer.addTo(mapping)
 	if err != nil {
 		return nil, err
 	}
+	mapping.DefaultAnalyzer = name
+	mapping.DefaultMapping = newDocumentMapping(name)
+	return mapping, nil
+}
 
-	err = mapping.AddCustomAnalyzer(customAnalyzerName,
-		map[string]interface{}{
-			"type":      custom.Name,
-			"tokenizer": customTokenizerName,
-		})
-	if err != nil {
-		return nil, err
+// newDocumentMapping for Document, where only path is searched by default
+// Name and directory segments are searched separately with their own boosts, such that basename matches could be favored
+// Other fields are not included in composite field, but they are stored and could be filtered or sorted with
+func newDocumentMapping(analyzer string) *mapping.DocumentMapping {
+	textField := func(includeInAll bool) *mapping.FieldMapping {
+		f := bleve.NewTextFieldMapping()
+		f.Analyzer = analyzer
+		f.IncludeInAll = includeInAll
+		return f
 	}
-	mapping.DefaultAnalyzer = customAnalyzerName
-	return mapping, nil
+	keywordField := func() *mapping.FieldMapping {
+		f := bleve.NewKeywordFieldMapping()
+		f.IncludeInAll = false
+		return f
+	}
+	numericField := func() *mapping.FieldMapping {
+		f := bleve.NewNumericFieldMapping()
+		f.IncludeInAll = false
+		return f
+	}
+	dateTimeField := func() *mapping.FieldMapping {
+		f := bleve.NewDateTimeFieldMapping()
+		f.IncludeInAll = false
+		return f
+	}
+	booleanField := func() *mapping.FieldMapping {
+		f := bleve.NewBooleanFieldMapping()
+		f.IncludeInAll = false
+		return f
+	}
+
+	segmentsField := textField(false)
+	segmentsField.Name = FieldSegments
+	segmentsField.Store = false
+
+	m := bleve.NewDocumentStaticMapping()
+	m.AddFieldMappingsAt(FieldPath, textField(true))
+	m.AddFieldMappingsAt(FieldName, textField(false))
+	m.AddFieldMappingsAt(FieldDir, keywordField(), segmentsField)
+	m.AddFieldMappingsAt(FieldExt, keywordField())
+	m.AddFieldMappingsAt(FieldSize, numericField())
+	m.AddFieldMappingsAt(FieldModTime, dateTimeField())
+	m.AddFieldMappingsAt(FieldIsDir, booleanField())
+	m.AddFieldMappingsAt(FieldLocation, keywordField())
+	return m
 }
 
 type indexer interface {
 	Index(id string, data interface{}) error
 }
 
-func newIndexWalkFunc(i indexer) walker.WalkFunc {
+func newIndexWalkFunc(i indexer, location string) walker.WalkFunc {
 	return func(path string, info walker.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		return i.Index(path, path)
+		return i.Index(path, newDocument(location, path, info))
+	}
+}
+
+// builder counts documents indexed, as bleve builder could not be closed without any document
+type builder struct {
+	bleve.Builder
+	count int64
+}
+
+func (b *builder) Index(id string, data interface{}) error {
+	err := b.Builder.Index(id, data)
+	if err == nil {
+		atomic.AddInt64(&b.count, 1)
+	}
+	return err
+}
+
+// close merges segments into index at path, or creates empty index instead if no document is indexed
+func (b *builder) close(path string, mapping mapping.IndexMapping) error {
+	if atomic.LoadInt64(&b.count) > 0 {
+		return b.Builder.Close()
+	}
+	err := os.RemoveAll(path)
+	if err != nil {
+		return err
+	}
+	index, err := bleve.New(path, mapping)
+	if err != nil {
+		return err
 	}
+	return index.Close()
 }
 
-func removeIndexesExclude(basePath string, name string) error {
+// removeIndexesExclude removes indexes other than specified ones and those in use by others, and returns names of removed ones
+// Caller should hold exclusive lock of base path
+func removeIndexesExclude(basePath string, names ...string) ([]string, error) {
 	entries, err := os.ReadDir(basePath)
 	if err != nil {
-		return fmt.Errorf("failed to read %v: %w", basePath, err)
+		return nil, fmt.Errorf("failed to read %v: %w", basePath, err)
 	}
+	var removed []string
 	for _, e := range entries {
-		if e.Name() == HeadFileName || e.Name() == name {
+		// indexes are directories, other files such as HEAD file are kept
+		if !e.IsDir() || contains(names, e.Name()) {
 			continue
 		}
 		path := filepath.Join(basePath, e.Name())
+		inUse, err := generationInUse(path)
+		if err != nil {
+			return removed, err
+		}
+		if inUse {
+			continue
+		}
 		err = os.RemoveAll(path)
 		if err != nil {
-			return fmt.Errorf("failed to clean up %v: %w", path, err)
+			return removed, fmt.Errorf("failed to clean up %v: %w", path, err)
 		}
+		removed = append(removed, e.Name())
 	}
-	return nil
+	return removed, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// locationIndexName of sub-index for location within generation directory, which is stable for the same location
+func locationIndexName(generation string, location string) string {
+	sum := sha256.Sum256([]byte(location))
+	return filepath.Join(generation, hex.EncodeToString(sum[:8]))
 }
 
-// Wrapper of bleve.IndexAlias for single index alias
-type singleIndexAlias struct {
-	alias bleve.IndexAlias
-	index bleve.Index
+// Wrapper of bleve.IndexAlias fanning out across sub-index of each location
+// Index of older versions is a single index for all locations, which is keyed by empty location
+type indexAlias struct {
+	generation string
+	alias      bleve.IndexAlias
+	indexes    map[string]bleve.Index
 }
 
-func newSingleIndexAlias(index bleve.Index) *singleIndexAlias {
-	return &singleIndexAlias{
-		alias: bleve.NewIndexAlias(index),
-		index: index,
+func newIndexAlias() *indexAlias {
+	return &indexAlias{
+		alias:   bleve.NewIndexAlias(),
+		indexes: make(map[string]bleve.Index),
 	}
 }
 
-func (s *singleIndexAlias) name() string {
-	if s.index == nil {
-		return ""
+// name of generation specified by HEAD, which the sub-indexes are opened for
+func (s *indexAlias) name() string {
+	return s.generation
+}
+
+// legacy checks if it is a single index for all locations of older versions
+func (s *indexAlias) legacy() bool {
+	_, ok := s.indexes[""]
+	return ok
+}
+
+// find opened sub-index by its name, such that the same sub-index is not opened twice
+func (s *indexAlias) find(name string) (bleve.Index, bool) {
+	for _, index := range s.indexes {
+		if index.Name() == name {
+			return index, true
+		}
 	}
-	return s.index.Name()
+	return nil, false
 }
 
-func (s *singleIndexAlias) close() error {
-	var indexCloseErr error
-	if s.index != nil {
-		indexCloseErr = s.index.Close()
+// get sub-index of location
+func (s *indexAlias) get(location string) (bleve.Index, bool) {
+	index, ok := s.indexes[location]
+	return index, ok
+}
+
+// generations returns names of generations containing the opened sub-indexes
+func (s *indexAlias) generations() []string {
+	return generationsOf(s.indexes)
+}
+
+// generationsOf sub-indexes keyed by location without duplicates, where index of older versions is the generation itself
+func generationsOf(indexes map[string]bleve.Index) []string {
+	var names []string
+	for location, index := range indexes {
+		name := index.Name()
+		if location != "" {
+			name = filepath.Dir(name)
+		}
+		if !contains(names, name) {
+			names = append(names, name)
+		}
 	}
-	var indexAliasCloseErr error
-	if s.alias != nil {
-		indexAliasCloseErr = s.alias.Close()
+	return names
+}
+
+func (s *indexAlias) close() error {
+	var indexCloseErr error
+	for _, index := range s.indexes {
+		err := index.Close()
+		if err != nil && indexCloseErr == nil {
+			indexCloseErr = err
+		}
 	}
+	indexAliasCloseErr := s.alias.Close()
 	if indexCloseErr != nil || indexAliasCloseErr != nil {
 		return fmt.Errorf("failed index close: %v, or failed index alias close: %v", indexCloseErr, indexAliasCloseErr)
 	}
 	return nil
 }
 
-func (s *singleIndexAlias) swap(in bleve.Index) {
-	ins := []bleve.Index{in}
-	outs := []bleve.Index{s.index}
-	s.index = in
+// swap sub-indexes with ones of specified generation atomically, and returns sub-indexes that are no longer used
+func (s *indexAlias) swap(generation string, indexes map[string]bleve.Index) []bleve.Index {
+	var ins, outs []bleve.Index
+	for location, index := range indexes {
+		if prev, ok := s.indexes[location]; !ok || prev != index {
+			ins = append(ins, index)
+		}
+	}
+	for location, prev := range s.indexes {
+		if index, ok := indexes[location]; !ok || prev != index {
+			outs = append(outs, prev)
+		}
+	}
+	s.generation = generation
+	s.indexes = indexes
 	s.alias.Swap(ins, outs)
+	return outs
 }
 
-func (s *singleIndexAlias) get() bleve.Index {
-	return s.index
+func (s *indexAlias) docCount() (uint64, error) {
+	return s.alias.DocCount()
 }
 
-func (s *singleIndexAlias) docCount() (uint64, error) {
-	return s.alias.DocCount()
+func (s *indexAlias) search(req *bleve.SearchRequest) (*bleve.SearchResult, error) {
+	res, err := s.alias.Search(req)
+	if err != nil {
+		return nil, err
+	}
+	dedupHits(res)
+	return res, nil
 }
 
-func (s *singleIndexAlias) search(req *bleve.SearchRequest) (*bleve.SearchResult, error) {
-	return s.alias.Search(req)
+// dedupHits removes hits of the same path from sub-indexes of overlapping locations, keeping the first ranked one
+func dedupHits(res *bleve.SearchResult) {
+	seen := make(map[string]bool, len(res.Hits))
+	hits := res.Hits[:0]
+	for _, hit := range res.Hits {
+		if seen[hit.ID] {
+			res.Total--
+			continue
+		}
+		seen[hit.ID] = true
+		hits = append(hits, hit)
+	}
+	res.Hits = hits
 }
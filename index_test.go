@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,9 +28,10 @@ func (m *mockIndexer) Index(id string, data interface{}) error {
 	return nil
 }
 
-func TestIndexWalkFuncIndexFileNameForBothIdandData(t *testing.T) {
+func TestIndexWalkFuncIndexFileNameAsIdAndDocumentAsData(t *testing.T) {
 	i := new(mockIndexer)
-	fn := newIndexWalkFunc(i)
+	location := filepath.Clean("/level0")
+	fn := newIndexWalkFunc(i, location)
 
 	path := filepath.Clean("/level0/level0.txt")
 	fileInfo := newMockFileInfo(filepath.Base(path), fileMode, false)
@@ -37,13 +40,13 @@ func TestIndexWalkFuncIndexFileNameForBothIdandData(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, []indexerCall{
-		{id: path, data: path},
+		{id: path, data: newDocument(location, path, fileInfo)},
 	}, i.calls)
 }
 
 func TestIndexWalkFuncReturnsErrorIfPassed(t *testing.T) {
 	i := new(mockIndexer)
-	fn := newIndexWalkFunc(i)
+	fn := newIndexWalkFunc(i, filepath.Clean("/level0"))
 
 	path := filepath.Clean("/level0/level0.txt")
 	fileInfo := newMockFileInfo(filepath.Base(path), fileMode, false)
@@ -79,8 +82,9 @@ func TestRemoveIndexesExceptHeadAndCurrent(t *testing.T) {
 	err = os.WriteFile(head, []byte("content"), fileMode)
 	assert.NoError(t, err)
 
-	err = removeIndexesExclude(dir, "index2")
+	removed, err := removeIndexesExclude(dir, "index2")
 	assert.NoError(t, err)
+	assert.Equal(t, []string{"index1"}, removed)
 
 	entries, err := os.ReadDir(dir)
 	assert.NoError(t, err)
@@ -92,3 +96,27 @@ func TestRemoveIndexesExceptHeadAndCurrent(t *testing.T) {
 	}
 	assert.ElementsMatch(t, []string{"index2", HeadFileName}, names)
 }
+
+func TestLocationIndexName(t *testing.T) {
+	name := locationIndexName("generation", "/a")
+	assert.Equal(t, "generation", filepath.Dir(name))
+	assert.Equal(t, name, locationIndexName("generation", "/a"), "name should be stable for the same location")
+	assert.NotEqual(t, name, locationIndexName("generation", "/b"))
+}
+
+func TestDedupHitsKeepsFirstRanked(t *testing.T) {
+	res := &bleve.SearchResult{
+		Total: 3,
+		Hits: search.DocumentMatchCollection{
+			{ID: "/a", Score: 3},
+			{ID: "/b", Score: 2},
+			{ID: "/a", Score: 1},
+		},
+	}
+	dedupHits(res)
+	assert.Equal(t, uint64(2), res.Total)
+	if assert.Len(t, res.Hits, 2) {
+		assert.Equal(t, 3.0, res.Hits[0].Score)
+		assert.Equal(t, "/b", res.Hits[1].ID)
+	}
+}
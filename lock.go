@@ -0,0 +1,268 @@
+package fzd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LockFileName is name of advisory lock file within base path, which coordinates processes sharing the base path
+const LockFileName = "LOCK"
+
+// DefaultLockTimeout is default duration for waiting the lock held by other processes
+const DefaultLockTimeout = 5 * time.Second
+
+// lockRetryInterval between attempts of acquiring the lock, as the lock is acquired without blocking
+const lockRetryInterval = 50 * time.Millisecond
+
+// Error where the lock of base path is held by another process
+var ErrIndexLocked = errors.New("index is locked by another process")
+
+// errWouldBlock is returned by platform specific lockFile if the lock is held by others
+var errWouldBlock = errors.New("lock would block")
+
+// baseLock is advisory lock of base path, where lock of the same Indexer is shared by reference counting
+// Shared lock is held briefly while HEAD file is read and indexes are being opened or created,
+// and exclusive lock is held while HEAD file is written or unused indexes are removed, such that writers are serialized
+// The same lock file within each generation is held shared while the generation is in use, i.e. opened or being built,
+// such that generations in use by others are never removed
+// Locks are released by the OS once the holding process exits, so a lock file left behind by a crashed process
+// is detected as stale by acquiring the lock, instead of by existence of the lock file
+type baseLock struct {
+	path    string
+	timeout time.Duration
+	mutex   sync.Mutex
+	file    *os.File
+	count   int
+}
+
+func newBaseLock(basePath string, timeout time.Duration) *baseLock {
+	return &baseLock{
+		path:    filepath.Join(basePath, LockFileName),
+		timeout: timeout,
+	}
+}
+
+// acquire shared lock, which waits for exclusive lock held by other processes up to timeout
+func (l *baseLock) acquire() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.count == 0 {
+		f, err := openLockFile(l.path)
+		if err != nil {
+			return err
+		}
+		err = l.wait(f, false)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		l.file = f
+	}
+	l.count++
+	return nil
+}
+
+// release shared lock, where the lock is only unlocked once all references are released
+func (l *baseLock) release() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.count == 0 {
+		return nil
+	}
+	l.count--
+	if l.count > 0 {
+		return nil
+	}
+	f := l.file
+	l.file = nil
+	err := unlockFile(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to unlock %v: %w", l.path, err)
+	}
+	return f.Close()
+}
+
+// tryExclusive runs fn with exclusive lock, and returns false without running fn if the lock is held by others,
+// including shared lock still referenced within the same process
+func (l *baseLock) tryExclusive(fn func() error) (bool, error) {
+	f, err := openLockFile(l.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	err = lockFile(f, true)
+	if errors.Is(err, errWouldBlock) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock %v: %w", l.path, err)
+	}
+	defer unlockFile(f)
+
+	// holder is recorded for error message of others waiting for the lock
+	err = writeLockHolder(f)
+	if err != nil {
+		return false, err
+	}
+	return true, fn()
+}
+
+// exclusive runs fn with exclusive lock, which waits for the lock held by others up to timeout
+// Shared lock still referenced within the same process is held by others as well, so it should be released before
+func (l *baseLock) exclusive(fn func() error) error {
+	f, err := openLockFile(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = l.wait(f, true)
+	if err != nil {
+		return err
+	}
+	defer unlockFile(f)
+
+	err = writeLockHolder(f)
+	if err != nil {
+		return err
+	}
+	return fn()
+}
+
+func (l *baseLock) wait(f *os.File, exclusive bool) error {
+	deadline := time.Now().Add(l.timeout)
+	for {
+		err := lockFile(f, exclusive)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errWouldBlock) {
+			return fmt.Errorf("failed to lock %v: %w", l.path, err)
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%w: timed out after %v waiting for %v%v", ErrIndexLocked, l.timeout, l.path, readLockHolder(f))
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// generationLocks are shared locks of generations in use by the Indexer, which are reference counted per generation
+type generationLocks struct {
+	basePath string
+	timeout  time.Duration
+	mutex    sync.Mutex
+	locks    map[string]*baseLock
+}
+
+func newGenerationLocks(basePath string, timeout time.Duration) *generationLocks {
+	return &generationLocks{
+		basePath: basePath,
+		timeout:  timeout,
+		locks:    make(map[string]*baseLock),
+	}
+}
+
+// acquire shared locks of generations, where none of them is held if any of them could not be acquired
+// Caller should hold lock of base path, such that generations could not be removed before being locked
+func (g *generationLocks) acquire(names ...string) error {
+	for n, name := range names {
+		err := g.get(name).acquire()
+		if err != nil {
+			g.release(names[:n]...)
+			return err
+		}
+	}
+	return nil
+}
+
+// release shared locks of generations acquired by acquire
+func (g *generationLocks) release(names ...string) error {
+	var err error
+	for _, name := range names {
+		releaseErr := g.get(name).release()
+		if releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}
+	return err
+}
+
+func (g *generationLocks) get(name string) *baseLock {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	l, ok := g.locks[name]
+	if !ok {
+		l = newBaseLock(filepath.Join(g.basePath, name), g.timeout)
+		g.locks[name] = l
+	}
+	return l
+}
+
+// generationInUse checks if lock of generation directory is held by others, where generations never locked are not in use
+// Caller should hold exclusive lock of base path, such that the generation could not be locked by others afterwards
+func generationInUse(path string) (bool, error) {
+	f, err := os.OpenFile(filepath.Join(path, LockFileName), os.O_RDWR, 0600)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock file of %v: %w", path, err)
+	}
+	// lock file is closed before the generation is removed, as open files could not be removed on Windows
+	defer f.Close()
+
+	err = lockFile(f, true)
+	if errors.Is(err, errWouldBlock) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock %v: %w", f.Name(), err)
+	}
+	return false, unlockFile(f)
+}
+
+// openLockFile opens the lock file, where base path is created if it does not exist yet, i.e. before first index
+func openLockFile(path string) (*os.File, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %v: %w", path, err)
+	}
+	return f, nil
+}
+
+func writeLockHolder(f *os.File) error {
+	err := f.Truncate(0)
+	if err == nil {
+		_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write lock holder to %v: %w", f.Name(), err)
+	}
+	return nil
+}
+
+// readLockHolder returns description of the last exclusive holder for error message, or empty if unknown
+func readLockHolder(f *os.File) string {
+	b := make([]byte, 32)
+	n, _ := f.ReadAt(b, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b[:n])))
+	if err != nil || pid <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (last held exclusively by pid %v)", pid)
+}
@@ -0,0 +1,188 @@
+package fzd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseLockSharedByOthers(t *testing.T) {
+	dir := t.TempDir()
+	l1 := newBaseLock(dir, 0)
+	l2 := newBaseLock(dir, 0)
+
+	assert.NoError(t, l1.acquire())
+	assert.NoError(t, l2.acquire())
+
+	ok, err := l1.tryExclusive(func() error {
+		t.Fatal("exclusive lock should not be acquired while shared lock is held")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, l1.release())
+	assert.NoError(t, l2.release())
+
+	ok, err = l1.tryExclusive(func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestBaseLockReferenceCounted(t *testing.T) {
+	dir := t.TempDir()
+	l := newBaseLock(dir, 0)
+
+	assert.NoError(t, l.acquire())
+	assert.NoError(t, l.acquire())
+	assert.NoError(t, l.release())
+
+	// lock is still held by the remaining reference
+	ok, err := l.tryExclusive(func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, l.release())
+	assert.NoError(t, l.release())
+	ok, err = l.tryExclusive(func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestBaseLockTimeoutWhileExclusive(t *testing.T) {
+	dir := t.TempDir()
+	l1 := newBaseLock(dir, 0)
+	l2 := newBaseLock(dir, 100*time.Millisecond)
+
+	ok, err := l1.tryExclusive(func() error {
+		start := time.Now()
+		err := l2.acquire()
+		assert.ErrorIs(t, err, ErrIndexLocked)
+		assert.Contains(t, err.Error(), "pid")
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// lock is acquired once exclusive lock is released
+	assert.NoError(t, l2.acquire())
+	assert.NoError(t, l2.release())
+}
+
+func TestBaseLockExclusiveWaitsForOthers(t *testing.T) {
+	dir := t.TempDir()
+	l1 := newBaseLock(dir, 0)
+	l2 := newBaseLock(dir, 100*time.Millisecond)
+
+	assert.NoError(t, l1.acquire())
+	err := l2.exclusive(func() error {
+		t.Fatal("exclusive lock should not be acquired while shared lock is held")
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrIndexLocked)
+
+	// lock is acquired once shared lock is released within timeout
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		l1.release()
+	}()
+	var ran bool
+	err = l2.exclusive(func() error {
+		ran = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ran)
+}
+
+func TestGenerationInUse(t *testing.T) {
+	dir := t.TempDir()
+	locks := newGenerationLocks(dir, 0)
+	assert.NoError(t, locks.acquire("gen1", "gen1"))
+
+	inUse, err := generationInUse(filepath.Join(dir, "gen1"))
+	assert.NoError(t, err)
+	assert.True(t, inUse)
+
+	// generation never locked, i.e. built by older versions, is not in use
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "gen2"), 0700))
+	inUse, err = generationInUse(filepath.Join(dir, "gen2"))
+	assert.NoError(t, err)
+	assert.False(t, inUse)
+
+	// generation is in use until all references are released
+	assert.NoError(t, locks.release("gen1"))
+	inUse, err = generationInUse(filepath.Join(dir, "gen1"))
+	assert.NoError(t, err)
+	assert.True(t, inUse)
+
+	assert.NoError(t, locks.release("gen1"))
+	inUse, err = generationInUse(filepath.Join(dir, "gen1"))
+	assert.NoError(t, err)
+	assert.False(t, inUse)
+}
+
+func TestBaseLockIgnoresStaleLockFile(t *testing.T) {
+	dir := t.TempDir()
+	// lock file left behind by crashed process does not hold the lock
+	err := os.WriteFile(filepath.Join(dir, LockFileName), []byte("99999999"), 0600)
+	assert.NoError(t, err)
+
+	l := newBaseLock(dir, 0)
+	assert.NoError(t, l.acquire())
+	assert.NoError(t, l.release())
+
+	ok, err := l.tryExclusive(func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestIndexerReturnsErrorIfLocked(t *testing.T) {
+	dir := t.TempDir()
+	indexer, err := NewIndexer(dir, WithLocation(t.TempDir(), LocationOption{}), WithLockTimeout(100*time.Millisecond))
+	assert.NoError(t, err)
+	name, err := indexer.Index()
+	assert.NoError(t, err)
+
+	// lock is held exclusively by another process writing HEAD file or removing unused indexes
+	ok, err := newBaseLock(dir, 0).tryExclusive(func() error {
+		err := indexer.Open()
+		assert.ErrorIs(t, err, ErrIndexLocked)
+
+		_, err = indexer.Index()
+		assert.ErrorIs(t, err, ErrIndexLocked)
+
+		err = indexer.OpenAndSwap(name)
+		assert.ErrorIs(t, err, ErrIndexLocked)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	err = indexer.Open()
+	assert.ErrorIs(t, err, ErrIndexHeadDoesNotExist)
+	err = indexer.OpenAndSwap(name)
+	assert.NoError(t, err)
+	assert.NoError(t, indexer.Close())
+}
+
+func TestBaseLockCreatesBasePath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "indexes")
+	l := newBaseLock(dir, 0)
+
+	assert.NoError(t, l.acquire())
+	assert.FileExists(t, filepath.Join(dir, LockFileName))
+	assert.NoError(t, l.release())
+}
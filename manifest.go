@@ -0,0 +1,161 @@
+package fzd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/horacehylee/fzd/walker"
+)
+
+const (
+	manifestLocationsKey = "fzd/manifest/locations"
+	manifestKeyPrefix    = "fzd/manifest/location/"
+)
+
+// manifest records modification time (in unix nano) of every indexed path for a location
+// It is stored within index internal storage, such that changes since last index can be detected
+type manifest map[string]int64
+
+func manifestKey(location string) []byte {
+	return []byte(manifestKeyPrefix + location)
+}
+
+type internalReader interface {
+	GetInternal(key []byte) ([]byte, error)
+}
+
+type internalWriter interface {
+	SetInternal(key, val []byte)
+	DeleteInternal(key []byte)
+}
+
+// readManifests returns manifests of all indexed locations
+// If manifests are not found, ErrIndexManifestDoesNotExist will be returned
+func readManifests(r internalReader) (map[string]manifest, error) {
+	content, err := r.GetInternal([]byte(manifestLocationsKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest locations: %w", err)
+	}
+	if content == nil {
+		return nil, ErrIndexManifestDoesNotExist
+	}
+	var locations []string
+	err = json.Unmarshal(content, &locations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest locations: %w", err)
+	}
+
+	manifests := make(map[string]manifest, len(locations))
+	for _, location := range locations {
+		content, err := r.GetInternal(manifestKey(location))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest of %v: %w", location, err)
+		}
+		m := make(manifest)
+		if content != nil {
+			err = json.Unmarshal(content, &m)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse manifest of %v: %w", location, err)
+			}
+		}
+		manifests[location] = m
+	}
+	return manifests, nil
+}
+
+// writeManifests stores manifests of all indexed locations
+// Manifests of locations that are no longer indexed will be removed
+func writeManifests(w internalWriter, prev map[string]manifest, manifests map[string]manifest) error {
+	locations := make([]string, 0, len(manifests))
+	for location, m := range manifests {
+		content, err := json.Marshal(m)
+		if err != nil {
+			return fmt.Errorf("failed to serialize manifest of %v: %w", location, err)
+		}
+		w.SetInternal(manifestKey(location), content)
+		locations = append(locations, location)
+	}
+	for location := range prev {
+		if _, ok := manifests[location]; !ok {
+			w.DeleteInternal(manifestKey(location))
+		}
+	}
+	content, err := json.Marshal(locations)
+	if err != nil {
+		return fmt.Errorf("failed to serialize manifest locations: %w", err)
+	}
+	w.SetInternal([]byte(manifestLocationsKey), content)
+	return nil
+}
+
+// writeIndexManifests opens index at specified path and stores manifests into it
+func writeIndexManifests(path string, manifests map[string]manifest) error {
+	index, err := bleve.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %v: %w", path, err)
+	}
+	batch := index.NewBatch()
+	err = writeManifests(batch, nil, manifests)
+	if err != nil {
+		index.Close()
+		return err
+	}
+	err = index.Batch(batch)
+	if err != nil {
+		index.Close()
+		return fmt.Errorf("failed to write manifests: %w", err)
+	}
+	return index.Close()
+}
+
+func newManifestWalkFunc(m manifest) walker.WalkFunc {
+	return func(path string, info walker.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		m[path] = info.ModTime().UnixNano()
+		return nil
+	}
+}
+
+// withChangedOnly calls fn only for paths that are added or modified since previous manifest
+// Unchanged paths are not skipped by SkipThis, so that entries within unchanged directories are still traversed
+func withChangedOnly(prev manifest, curr manifest, fn walker.WalkFunc) walker.WalkFunc {
+	return func(path string, info walker.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if mtime, ok := prev[path]; ok && mtime == curr[path] {
+			return nil
+		}
+		return fn(path, info, err)
+	}
+}
+
+// Delta of changes applied to index by incremental reindex
+type Delta struct {
+	Added   int `json:"added"`
+	Changed int `json:"changed"`
+	Removed int `json:"removed"`
+}
+
+func diffManifests(prev manifest, curr manifest) (Delta, []string) {
+	var delta Delta
+	var removed []string
+	for path, mtime := range curr {
+		prevMtime, ok := prev[path]
+		if !ok {
+			delta.Added++
+		} else if prevMtime != mtime {
+			delta.Changed++
+		}
+	}
+	for path := range prev {
+		if _, ok := curr[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	delta.Removed = len(removed)
+	return delta, removed
+}
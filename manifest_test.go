@@ -0,0 +1,112 @@
+package fzd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/horacehylee/fzd/walker"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockInternal struct {
+	internal map[string][]byte
+}
+
+func newMockInternal() *mockInternal {
+	return &mockInternal{internal: make(map[string][]byte)}
+}
+
+func (m *mockInternal) GetInternal(key []byte) ([]byte, error) {
+	return m.internal[string(key)], nil
+}
+
+func (m *mockInternal) SetInternal(key, val []byte) {
+	m.internal[string(key)] = val
+}
+
+func (m *mockInternal) DeleteInternal(key []byte) {
+	delete(m.internal, string(key))
+}
+
+func TestWriteAndReadManifests(t *testing.T) {
+	m := newMockInternal()
+	manifests := map[string]manifest{
+		"/level0": {"/level0": 1, "/level0/level0.txt": 2},
+		"/other":  {},
+	}
+
+	err := writeManifests(m, nil, manifests)
+	assert.NoError(t, err)
+
+	read, err := readManifests(m)
+	assert.NoError(t, err)
+	assert.Equal(t, manifests, read)
+}
+
+func TestWriteManifestsRemovesPreviousLocations(t *testing.T) {
+	m := newMockInternal()
+	prev := map[string]manifest{
+		"/level0": {"/level0": 1},
+		"/other":  {"/other": 1},
+	}
+	err := writeManifests(m, nil, prev)
+	assert.NoError(t, err)
+
+	manifests := map[string]manifest{
+		"/level0": {"/level0": 2},
+	}
+	err = writeManifests(m, prev, manifests)
+	assert.NoError(t, err)
+
+	read, err := readManifests(m)
+	assert.NoError(t, err)
+	assert.Equal(t, manifests, read)
+	assert.NotContains(t, m.internal, string(manifestKey("/other")))
+}
+
+func TestReadManifestsReturnsErrorIfNotExist(t *testing.T) {
+	_, err := readManifests(newMockInternal())
+	assert.ErrorIs(t, err, ErrIndexManifestDoesNotExist)
+}
+
+func TestChangedOnlyWalkFunc(t *testing.T) {
+	prev := manifest{"/unchanged": 1, "/changed": 1}
+	curr := manifest{"/unchanged": 1, "/changed": 2, "/added": 1}
+
+	var called []string
+	fn := withChangedOnly(prev, curr, func(path string, info walker.FileInfo, err error) error {
+		called = append(called, path)
+		return nil
+	})
+
+	for _, path := range []string{"/unchanged", "/changed", "/added"} {
+		fileInfo := newMockFileInfo(filepath.Base(path), fileMode, false)
+		err := fn(path, fileInfo, nil)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"/changed", "/added"}, called)
+}
+
+func TestChangedOnlyWalkFuncReturnsErrorIfPassed(t *testing.T) {
+	fn := withChangedOnly(manifest{}, manifest{}, func(path string, info walker.FileInfo, err error) error {
+		assert.Fail(t, "should not be called")
+		return nil
+	})
+
+	path := "/added"
+	fileInfo := newMockFileInfo(filepath.Base(path), fileMode, false)
+	e := errors.New("test error")
+
+	err := fn(path, fileInfo, e)
+	assert.Equal(t, e, err)
+}
+
+func TestDiffManifests(t *testing.T) {
+	prev := manifest{"/unchanged": 1, "/changed": 1, "/removed": 1}
+	curr := manifest{"/unchanged": 1, "/changed": 2, "/added": 1}
+
+	delta, removed := diffManifests(prev, curr)
+	assert.Equal(t, Delta{Added: 1, Changed: 1, Removed: 1}, delta)
+	assert.Equal(t, []string{"/removed"}, removed)
+}
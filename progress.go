@@ -0,0 +1,126 @@
+package fzd
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/horacehylee/fzd/walker"
+)
+
+// DefaultProgressInterval is default interval between reports of indexing progress
+const DefaultProgressInterval = 100 * time.Millisecond
+
+// IndexOptions for options on indexing with IndexContext and ReindexContext
+type IndexOptions struct {
+	// Progress is called periodically while walking locations, and once more after all locations are walked
+	// It is called from a single goroutine, such that it is not required to be concurrent-safe
+	Progress func(Progress)
+
+	// ProgressInterval is interval between calls of Progress, DefaultProgressInterval is used if not specified
+	ProgressInterval time.Duration
+
+	// Locations to be rebuilt by IndexContext, where sub-indexes of other locations are carried over from HEAD file
+	// All locations are rebuilt if not specified, and it is ignored by ReindexContext
+	Locations []string
+}
+
+// Progress of indexing
+type Progress struct {
+	// Dirs is number of directories visited
+	Dirs int64 `json:"dirs"`
+
+	// Files is number of files indexed, excluding those filtered or skipped
+	Files int64 `json:"files"`
+
+	// Location of the path visited most recently, as locations are walked concurrently
+	Location string `json:"location"`
+
+	// Elapsed is duration since indexing is started
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// tracker counts progress of walking locations concurrently
+type tracker struct {
+	start    time.Time
+	dirs     int64
+	files    int64
+	location atomic.Value
+}
+
+func newTracker() *tracker {
+	t := &tracker{start: time.Now()}
+	t.location.Store("")
+	return t
+}
+
+// walkFunc wraps WalkFunc of location, such that visited directories and indexed files are counted
+func (t *tracker) walkFunc(location string, fn walker.WalkFunc) walker.WalkFunc {
+	return func(path string, info walker.FileInfo, err error) error {
+		if err == nil {
+			t.location.Store(location)
+			if info.IsDir() {
+				atomic.AddInt64(&t.dirs, 1)
+			}
+		}
+		err = fn(path, info, err)
+		if err == nil && info != nil && !info.IsDir() {
+			atomic.AddInt64(&t.files, 1)
+		}
+		return err
+	}
+}
+
+func (t *tracker) progress() Progress {
+	return Progress{
+		Dirs:     atomic.LoadInt64(&t.dirs),
+		Files:    atomic.LoadInt64(&t.files),
+		Location: t.location.Load().(string),
+		Elapsed:  time.Since(t.start),
+	}
+}
+
+// report calls Progress of options periodically until returned stop function is called
+// Stop function reports the final progress, and waits for the reporting goroutine to be stopped
+func (t *tracker) report(opts IndexOptions) (stop func()) {
+	if opts.Progress == nil {
+		return func() {}
+	}
+	interval := opts.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				opts.Progress(t.progress())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+		opts.Progress(t.progress())
+	}
+}
+
+// withContext stops the walk once context is done
+func withContext(ctx context.Context, fn walker.WalkFunc) walker.WalkFunc {
+	return func(path string, info walker.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fn(path, info, err)
+	}
+}
@@ -0,0 +1,77 @@
+package fzd
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/horacehylee/fzd/walker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackerCountsDirsAndFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	err := os.WriteFile(file, []byte("content"), 0600)
+	assert.NoError(t, err)
+	dirInfo, err := os.Stat(dir)
+	assert.NoError(t, err)
+	fileInfo, err := os.Stat(file)
+	assert.NoError(t, err)
+
+	tr := newTracker()
+	fn := tr.walkFunc("/root", func(path string, info walker.FileInfo, err error) error {
+		if path == "/root/filtered" {
+			return walker.SkipThis
+		}
+		return err
+	})
+
+	assert.NoError(t, fn("/root", dirInfo, nil))
+	assert.NoError(t, fn("/root/file", fileInfo, nil))
+	assert.ErrorIs(t, fn("/root/filtered", fileInfo, nil), walker.SkipThis)
+	assert.Error(t, fn("/root/denied", nil, fs.ErrPermission))
+
+	p := tr.progress()
+	assert.Equal(t, int64(1), p.Dirs)
+	assert.Equal(t, int64(1), p.Files)
+	assert.Equal(t, "/root", p.Location)
+}
+
+func TestTrackerReportsUntilStopped(t *testing.T) {
+	tr := newTracker()
+	stop := tr.report(IndexOptions{})
+	stop()
+
+	var progresses []Progress
+	stop = tr.report(IndexOptions{
+		Progress: func(p Progress) {
+			progresses = append(progresses, p)
+		},
+		ProgressInterval: time.Millisecond,
+	})
+	time.Sleep(10 * time.Millisecond)
+	stop()
+	n := len(progresses)
+	assert.GreaterOrEqual(t, n, 2)
+
+	// no more progress is reported once stopped
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, progresses, n)
+}
+
+func TestWithContextStopsWalk(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errFailed := errors.New("failed")
+	fn := withContext(ctx, func(path string, info walker.FileInfo, err error) error {
+		return errFailed
+	})
+
+	assert.ErrorIs(t, fn("/root", nil, nil), errFailed)
+	cancel()
+	assert.ErrorIs(t, fn("/root", nil, nil), context.Canceled)
+}
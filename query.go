@@ -0,0 +1,159 @@
+package fzd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys of filters within query, i.e. "ext:pdf type:dir in:~/Projects mtime:<7d report"
+const (
+	queryKeyType  = "type"
+	queryKeyExt   = "ext"
+	queryKeyIn    = "in"
+	queryKeyMTime = "mtime"
+)
+
+// dateLayout of dates for modification time filter
+const dateLayout = "2006-01-02"
+
+// ParseQuery extracts filters from query into options, and returns the remaining words as term to be searched
+// Filters are words of key:value, where words of other keys are kept in term, i.e. fields of query string syntax
+//
+//	type:file or type:dir matches type of file entries
+//	ext:pdf or ext:pdf,docx matches any of extensions, and could be specified more than once
+//	in:~/Projects matches file entries within directory, and could be specified more than once
+//	mtime:<7d or mtime:>7d matches file entries modified within or before duration, with units of s, m, h, d and w
+//	mtime:>2022-01-02 or mtime:<2022-01-02 matches file entries modified after or before date, or at date without operator
+func ParseQuery(input string, opts *SearchOptions) (string, error) {
+	return parseQuery(input, opts, time.Now())
+}
+
+func parseQuery(input string, opts *SearchOptions, now time.Time) (string, error) {
+	var words []string
+	for _, word := range strings.Fields(input) {
+		parts := strings.SplitN(word, ":", 2)
+		if len(parts) != 2 {
+			words = append(words, word)
+			continue
+		}
+		key, value := strings.ToLower(parts[0]), parts[1]
+		var err error
+		switch key {
+		case queryKeyType:
+			err = parseTypeFilter(value, opts)
+		case queryKeyExt:
+			err = parseExtFilter(value, opts)
+		case queryKeyIn:
+			err = parseInFilter(value, opts)
+		case queryKeyMTime:
+			err = parseMTimeFilter(value, opts, now)
+		default:
+			words = append(words, word)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid filter \"%v\": %w", word, err)
+		}
+	}
+	return strings.Join(words, " "), nil
+}
+
+func parseTypeFilter(value string, opts *SearchOptions) error {
+	t := FileType(strings.ToLower(value))
+	if t != TypeFile && t != TypeDir {
+		return fmt.Errorf("type should be either %v or %v", TypeFile, TypeDir)
+	}
+	opts.Type = t
+	return nil
+}
+
+func parseExtFilter(value string, opts *SearchOptions) error {
+	for _, ext := range strings.Split(value, ",") {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		if ext == "" {
+			return fmt.Errorf("extension cannot be blank")
+		}
+		opts.Exts = append(opts.Exts, ext)
+	}
+	return nil
+}
+
+// parseInFilter resolves directory into absolute path, where leading ~ is expanded into home directory
+func parseInFilter(value string, opts *SearchOptions) error {
+	if value == "" {
+		return fmt.Errorf("directory cannot be blank")
+	}
+	if value == "~" || strings.HasPrefix(value, "~/") || strings.HasPrefix(value, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		value = filepath.Join(home, value[1:])
+	}
+	dir, err := filepath.Abs(value)
+	if err != nil {
+		return err
+	}
+	opts.In = append(opts.In, dir)
+	return nil
+}
+
+// parseMTimeFilter of duration compared with age of file entries, or date compared with modification time
+func parseMTimeFilter(value string, opts *SearchOptions, now time.Time) error {
+	op := ""
+	if strings.HasPrefix(value, "<") || strings.HasPrefix(value, ">") {
+		op, value = value[:1], value[1:]
+	}
+
+	date, err := time.ParseInLocation(dateLayout, value, now.Location())
+	if err == nil {
+		switch op {
+		case "<":
+			opts.ModifiedBefore = date.Add(-time.Nanosecond)
+		case ">":
+			opts.ModifiedAfter = date.AddDate(0, 0, 1)
+		default:
+			opts.ModifiedAfter = date
+			opts.ModifiedBefore = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return nil
+	}
+
+	age, err := parseAge(value)
+	if err != nil {
+		return fmt.Errorf("should be duration i.e. 7d, or date i.e. %v", dateLayout)
+	}
+	switch op {
+	case ">":
+		opts.ModifiedBefore = now.Add(-age)
+	default:
+		opts.ModifiedAfter = now.Add(-age)
+	}
+	return nil
+}
+
+// parseAge parses duration with units of days and weeks in addition to units of time.ParseDuration
+func parseAge(value string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if strings.HasSuffix(value, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(value, suffix))
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration: %v", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration: %v", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,82 @@
+package fzd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2022, 1, 10, 12, 0, 0, 0, time.UTC)
+	home, err := os.UserHomeDir()
+	assert.NoError(t, err)
+
+	var opts SearchOptions
+	term, err := parseQuery("ext:PDF,.docx type:dir in:~/Projects mtime:<7d report +name:final", &opts, now)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "report +name:final", term)
+	assert.Equal(t, []string{"pdf", "docx"}, opts.Exts)
+	assert.Equal(t, TypeDir, opts.Type)
+	assert.Equal(t, []string{filepath.Join(home, "Projects")}, opts.In)
+	assert.Equal(t, now.Add(-7*24*time.Hour), opts.ModifiedAfter)
+	assert.True(t, opts.ModifiedBefore.IsZero())
+}
+
+func TestParseQueryOnlyFilters(t *testing.T) {
+	var opts SearchOptions
+	term, err := parseQuery("type:file in:/a in:/b", &opts, time.Now())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "", term)
+	assert.Equal(t, TypeFile, opts.Type)
+	assert.Equal(t, []string{filepath.Clean("/a"), filepath.Clean("/b")}, opts.In)
+}
+
+func TestParseQueryModifiedTime(t *testing.T) {
+	now := time.Date(2022, 1, 10, 12, 0, 0, 0, time.UTC)
+	date := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		after  time.Time
+		before time.Time
+	}{
+		{"2w", now.Add(-14 * 24 * time.Hour), time.Time{}},
+		{">36h", time.Time{}, now.Add(-36 * time.Hour)},
+		{"<2022-01-02", time.Time{}, date.Add(-time.Nanosecond)},
+		{">2022-01-02", date.AddDate(0, 0, 1), time.Time{}},
+		{"2022-01-02", date, date.AddDate(0, 0, 1).Add(-time.Nanosecond)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var opts SearchOptions
+			_, err := parseQuery("mtime:"+tt.value, &opts, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.after, opts.ModifiedAfter)
+			assert.Equal(t, tt.before, opts.ModifiedBefore)
+		})
+	}
+}
+
+func TestParseQueryReturnsErrorIfInvalid(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"type:link", "invalid filter \"type:link\": type should be either file or dir"},
+		{"ext:", "invalid filter \"ext:\": extension cannot be blank"},
+		{"in:", "invalid filter \"in:\": directory cannot be blank"},
+		{"mtime:<7x", "invalid filter \"mtime:<7x\": should be duration i.e. 7d, or date i.e. 2006-01-02"},
+		{"mtime:-1d", "invalid filter \"mtime:-1d\": should be duration i.e. 7d, or date i.e. 2006-01-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var opts SearchOptions
+			_, err := parseQuery(tt.input, &opts, time.Now())
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package fzd
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+const (
+	// DefaultPathBonus is default weight of bonuses for paths matching the term, which are multiplied into text score of hits
+	DefaultPathBonus = 1.0
+
+	// rankWindow is number of top hits to be reranked with path bonuses and frecency, such that better ranked paths
+	// could be ranked higher even if its text score is not within the limit
+	// It is fixed regardless of offset, such that paging through hits neither repeats nor skips any of them
+	rankWindow = 100
+
+	// exactNameBonus is bonus for basename, or basename without extension, equal to the term
+	exactNameBonus = 1.0
+
+	// nameStartBonus is bonus for basename starting with the term
+	nameStartBonus = 0.5
+
+	// segmentStartBonus is bonus for any of parent directory segments starting with the term
+	segmentStartBonus = 0.25
+
+	// depthBonus is bonus divided by number of segments of path, such that shallower paths are favored
+	depthBonus = 0.5
+)
+
+// rankPaths rescales score of hits with weighted bonus of their paths, and sorts hits by descending score
+func rankPaths(res *bleve.SearchResult, term string, weight float64) {
+	res.MaxScore = 0
+	for _, h := range res.Hits {
+		h.Score *= 1 + weight*pathBonus(term, h.ID)
+		res.MaxScore = math.Max(res.MaxScore, h.Score)
+	}
+	sortHits(res.Hits)
+}
+
+// pathBonus of path matching the term case insensitively, where the best match among words of the term is taken
+func pathBonus(term string, path string) float64 {
+	segments := strings.FieldsFunc(strings.ToLower(path), isPathSeparator)
+	if len(segments) == 0 {
+		return 0
+	}
+	name := segments[len(segments)-1]
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	dirs := segments[:len(segments)-1]
+
+	var bonus float64
+	for _, word := range strings.Fields(strings.ToLower(term)) {
+		var b float64
+		switch {
+		case word == name || word == stem:
+			b = exactNameBonus
+		case strings.HasPrefix(name, word):
+			b = nameStartBonus
+		case anyHasPrefix(dirs, word):
+			b = segmentStartBonus
+		}
+		bonus = math.Max(bonus, b)
+	}
+	return bonus + depthBonus/float64(len(segments))
+}
+
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+func anyHasPrefix(segments []string, prefix string) bool {
+	for _, s := range segments {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package fzd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files of ranking tests")
+
+func TestPathBonus(t *testing.T) {
+	tests := []struct {
+		term     string
+		path     string
+		expected float64
+	}{
+		{"test", "/home/test.json", exactNameBonus + depthBonus/2},
+		{"TEST", "/home/test", exactNameBonus + depthBonus/2},
+		{"test", "/home/tests.json", nameStartBonus + depthBonus/2},
+		{"test", "/home/test/data.json", segmentStartBonus + depthBonus/3},
+		{"test", "/home/zzz_test", depthBonus / 2},
+		{"data test", "/home/test/data.json", exactNameBonus + depthBonus/3},
+		{"test", "/", 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v in %v", tt.term, tt.path), func(t *testing.T) {
+			assert.InDelta(t, tt.expected, pathBonus(tt.term, tt.path), 1e-9)
+		})
+	}
+}
+
+func TestRankPaths(t *testing.T) {
+	res := newSearchResult(map[string]float64{"/home/test/a.json": 2, "/home/test.json": 1.5}, "/home/test/a.json", "/home/test.json")
+	rankPaths(res, "test", 1)
+
+	assert.Equal(t, "/home/test.json", res.Hits[0].ID)
+	assert.Equal(t, "/home/test/a.json", res.Hits[1].ID)
+	assert.Equal(t, res.Hits[0].Score, res.MaxScore)
+}
+
+// rankingFixtures are paths of fixture tree relative to its root, where directories are suffixed with "/"
+var rankingFixtures = []string{
+	"test.json",
+	"Projects/archive/zzz-test/main.go",
+	"Projects/zzz_test/README.md",
+	"Projects/file_tests/case1.txt",
+	"Projects/more_file_tests/case2.txt",
+	"Projects/fzd/search.go",
+	"Projects/fzd/search_test.go",
+	"Projects/fzd/README.md",
+	"Projects/fzd/cmd/fzd/main.go",
+	"Projects/fzd/testdata/ranking.golden",
+	"Projects/website/src/components/SearchBar.tsx",
+	"Projects/website/src/main.ts",
+	"Documents/report.pdf",
+	"Documents/reports/2021/annual-report.docx",
+	"Documents/reports/2021/summary.xlsx",
+	"Documents/archive/taxes/2021/receipts/",
+	"Downloads/main.zip",
+	"Music/test/track01.mp3",
+	"test/fixtures/data.json",
+	"test/unit/helper_test.go",
+}
+
+// rankingTerms are searched against fixture tree, where top hits of each are compared with golden file
+var rankingTerms = []string{
+	"test",
+	"report",
+	"main",
+	"README",
+	"search",
+	"2021",
+}
+
+// TestRankingGolden compares ranking of hits with golden file, which could be updated with -update flag
+func TestRankingGolden(t *testing.T) {
+	root, err := os.MkdirTemp("", "home")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	for _, f := range rankingFixtures {
+		path := filepath.Join(root, filepath.FromSlash(f))
+		if strings.HasSuffix(f, "/") {
+			assert.NoError(t, os.MkdirAll(path, 0700))
+			continue
+		}
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NoError(t, os.WriteFile(path, []byte("content"), 0700))
+	}
+
+	basePath, err := os.MkdirTemp("", "testRankingIndexes")
+	assert.NoError(t, err)
+	defer os.RemoveAll(basePath)
+
+	indexer, err := NewIndexer(basePath, WithLocation(root, LocationOption{}))
+	assert.NoError(t, err)
+	defer indexer.Close()
+	name, err := indexer.Index()
+	assert.NoError(t, err)
+	assert.NoError(t, indexer.OpenAndSwap(name))
+
+	var b strings.Builder
+	for _, term := range rankingTerms {
+		opts := DefaultSearchOptions()
+		opts.Limit = 5
+		res, err := indexer.SearchWithOptions(term, opts)
+		assert.NoError(t, err)
+
+		fmt.Fprintf(&b, "%v:\n", term)
+		for _, h := range res.Hits {
+			rel, err := filepath.Rel(root, h.ID)
+			assert.NoError(t, err)
+			fmt.Fprintf(&b, "  %v\n", filepath.ToSlash(rel))
+		}
+	}
+
+	golden := filepath.Join("testdata", "ranking.golden")
+	if *update {
+		assert.NoError(t, os.MkdirAll("testdata", 0700))
+		assert.NoError(t, os.WriteFile(golden, []byte(b.String()), 0600))
+	}
+	expected, err := os.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), b.String())
+}
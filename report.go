@@ -0,0 +1,223 @@
+package fzd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"github.com/horacehylee/fzd/walker"
+)
+
+// ErrorKind of error occurred while indexing a path
+type ErrorKind string
+
+const (
+	// PermissionError where path is not permitted to be read
+	PermissionError ErrorKind = "permission"
+
+	// NotExistError where path is removed while walking, or location does not exist
+	NotExistError ErrorKind = "not_exist"
+
+	// FilterError where filters or ignores of location are invalid, so the whole location is skipped
+	FilterError ErrorKind = "filter"
+
+	// IndexError where document of path failed to be indexed
+	IndexError ErrorKind = "index"
+
+	// OtherError for errors of other kinds
+	OtherError ErrorKind = "other"
+)
+
+// PathError of path skipped while indexing location
+type PathError struct {
+	Location string
+	Path     string
+	Kind     ErrorKind
+	Err      error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+type pathErrorJSON struct {
+	Location string    `json:"location"`
+	Path     string    `json:"path"`
+	Kind     ErrorKind `json:"kind"`
+	Error    string    `json:"error"`
+}
+
+// MarshalJSON with error message, as error could not be serialized
+func (e *PathError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pathErrorJSON{
+		Location: e.Location,
+		Path:     e.Path,
+		Kind:     e.Kind,
+		Error:    e.Err.Error(),
+	})
+}
+
+// UnmarshalJSON with error message, where error could only be compared by message
+func (e *PathError) UnmarshalJSON(data []byte) error {
+	var v pathErrorJSON
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*e = PathError{
+		Location: v.Location,
+		Path:     v.Path,
+		Kind:     v.Kind,
+		Err:      errors.New(v.Error),
+	}
+	return nil
+}
+
+// LocationReport of number of paths walked for the location
+type LocationReport struct {
+	// Paths is number of paths indexed, excluding those filtered or skipped
+	Paths int `json:"paths"`
+
+	// Skipped is number of paths skipped due to errors, where entries within skipped directories are not counted
+	Skipped int `json:"skipped"`
+}
+
+// IndexReport of paths walked for each location and errors occurred, as indexing continues on errors unless fail fast
+type IndexReport struct {
+	Locations map[string]LocationReport `json:"locations"`
+	Errors    []*PathError              `json:"errors"`
+}
+
+// ErrorsByKind returns number of errors for each kind
+func (r *IndexReport) ErrorsByKind() map[ErrorKind]int {
+	kinds := make(map[ErrorKind]int)
+	for _, e := range r.Errors {
+		kinds[e.Kind]++
+	}
+	return kinds
+}
+
+// Paths returns total number of paths indexed for all locations
+func (r *IndexReport) Paths() int {
+	total := 0
+	for _, l := range r.Locations {
+		total += l.Paths
+	}
+	return total
+}
+
+// reporter collects errors into IndexReport while walking locations concurrently
+type reporter struct {
+	failFast bool
+	mutex    sync.Mutex
+	report   IndexReport
+}
+
+func newReporter(failFast bool) *reporter {
+	return &reporter{
+		failFast: failFast,
+		report: IndexReport{
+			Locations: make(map[string]LocationReport),
+		},
+	}
+}
+
+// walkFunc wraps WalkFunc of location, such that errors of walking and fn are collected
+// Erroneous path is skipped, unless fail fast where PathError is returned to stop the walk
+func (r *reporter) walkFunc(location string, fn walker.WalkFunc) walker.WalkFunc {
+	return func(path string, info walker.FileInfo, err error) error {
+		if err != nil {
+			return r.fail(location, path, errorKind(err), err)
+		}
+		err = fn(path, info, nil)
+		if errors.Is(err, walker.SkipThis) {
+			return err
+		}
+		if err != nil {
+			return r.fail(location, path, IndexError, err)
+		}
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		l := r.report.Locations[location]
+		l.Paths++
+		r.report.Locations[location] = l
+		return nil
+	}
+}
+
+// fail records error of path, and returns SkipThis to skip the path, or PathError if fail fast
+func (r *reporter) fail(location string, path string, kind ErrorKind, err error) error {
+	e := &PathError{
+		Location: location,
+		Path:     path,
+		Kind:     kind,
+		Err:      err,
+	}
+	if r.failFast {
+		return e
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	l := r.report.Locations[location]
+	l.Skipped++
+	r.report.Locations[location] = l
+	r.report.Errors = append(r.report.Errors, e)
+	return walker.SkipThis
+}
+
+// skipped returns skipped paths of location
+func (r *reporter) skipped(location string) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var paths []string
+	for _, e := range r.report.Errors {
+		if e.Location == location {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths
+}
+
+// result returns the report, where errors are sorted by path as locations are walked concurrently
+func (r *reporter) result() *IndexReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	report := r.report
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Path < report.Errors[j].Path
+	})
+	return &report
+}
+
+func errorKind(err error) ErrorKind {
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return PermissionError
+	case errors.Is(err, fs.ErrNotExist):
+		return NotExistError
+	default:
+		return OtherError
+	}
+}
+
+// carryOver keeps previous manifest entries of skipped paths and their descendants, such that they are not
+// considered as removed, i.e. for a directory that is temporarily not permitted to be read
+func carryOver(prev manifest, curr manifest, skipped []string) {
+	for _, s := range skipped {
+		for path, mtime := range prev {
+			if isWithin(s, path) {
+				curr[path] = mtime
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package fzd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/horacehylee/fzd/walker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKind(t *testing.T) {
+	assert.Equal(t, PermissionError, errorKind(fmt.Errorf("wrapped: %w", fs.ErrPermission)))
+	assert.Equal(t, NotExistError, errorKind(&fs.PathError{Op: "lstat", Path: "/missing", Err: fs.ErrNotExist}))
+	assert.Equal(t, OtherError, errorKind(errors.New("other")))
+}
+
+func TestReporterSkipsAndReportsErrors(t *testing.T) {
+	r := newReporter(false)
+	errIndex := errors.New("failed to index")
+	fn := r.walkFunc("/root", func(path string, info walker.FileInfo, err error) error {
+		switch path {
+		case "/root/filtered":
+			return walker.SkipThis
+		case "/root/unindexable":
+			return errIndex
+		}
+		return nil
+	})
+
+	assert.NoError(t, fn("/root", nil, nil))
+	assert.NoError(t, fn("/root/file", nil, nil))
+	assert.ErrorIs(t, fn("/root/filtered", nil, nil), walker.SkipThis)
+	assert.ErrorIs(t, fn("/root/unindexable", nil, nil), walker.SkipThis)
+	assert.ErrorIs(t, fn("/root/denied", nil, fs.ErrPermission), walker.SkipThis)
+
+	report := r.result()
+	assert.Equal(t, map[string]LocationReport{"/root": {Paths: 2, Skipped: 2}}, report.Locations)
+	assert.Equal(t, 2, report.Paths())
+	assert.Equal(t, map[ErrorKind]int{IndexError: 1, PermissionError: 1}, report.ErrorsByKind())
+	if assert.Len(t, report.Errors, 2) {
+		assert.Equal(t, "/root/denied", report.Errors[0].Path)
+		assert.ErrorIs(t, report.Errors[0], fs.ErrPermission)
+		assert.Equal(t, "/root/unindexable", report.Errors[1].Path)
+		assert.ErrorIs(t, report.Errors[1], errIndex)
+	}
+	assert.ElementsMatch(t, []string{"/root/unindexable", "/root/denied"}, r.skipped("/root"))
+	assert.Empty(t, r.skipped("/other"))
+}
+
+func TestReporterFailFast(t *testing.T) {
+	r := newReporter(true)
+	fn := r.walkFunc("/root", func(path string, info walker.FileInfo, err error) error {
+		return nil
+	})
+
+	err := fn("/root/denied", nil, fs.ErrPermission)
+	var e *PathError
+	if assert.ErrorAs(t, err, &e) {
+		assert.Equal(t, "/root", e.Location)
+		assert.Equal(t, "/root/denied", e.Path)
+		assert.Equal(t, PermissionError, e.Kind)
+	}
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.Empty(t, r.result().Errors)
+}
+
+func TestPathErrorJSON(t *testing.T) {
+	e := &PathError{Location: "/root", Path: "/root/denied", Kind: PermissionError, Err: fs.ErrPermission}
+	content, err := json.Marshal(e)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"location":"/root","path":"/root/denied","kind":"permission","error":"permission denied"}`, string(content))
+
+	var decoded PathError
+	err = json.Unmarshal(content, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, e.Error(), decoded.Error())
+	assert.Equal(t, e.Kind, decoded.Kind)
+}
+
+func TestCarryOver(t *testing.T) {
+	prev := manifest{
+		"/root":                 1,
+		"/root/denied":          2,
+		"/root/denied/file.txt": 3,
+		"/root/deniedSibling":   4,
+		"/root/removed":         5,
+	}
+	curr := manifest{
+		"/root":        10,
+		"/root/denied": 20,
+	}
+	carryOver(prev, curr, []string{"/root/denied"})
+	assert.Equal(t, manifest{
+		"/root":                 10,
+		"/root/denied":          2,
+		"/root/denied/file.txt": 3,
+	}, curr)
+}
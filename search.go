@@ -0,0 +1,331 @@
+package fzd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// QueryKind of query to be combined for searching term
+type QueryKind string
+
+const (
+	// Fuzzy matches terms within edit distance of fuzziness
+	Fuzzy QueryKind = "fuzzy"
+
+	// Prefix matches terms starting with the term
+	Prefix QueryKind = "prefix"
+
+	// QueryString parses the term with bleve query string syntax
+	QueryString QueryKind = "query_string"
+
+	// Wildcard matches terms with wildcard pattern, with "*" and "?" as wildcards
+	Wildcard QueryKind = "wildcard"
+
+	// Match analyzes the term and matches terms with it
+	Match QueryKind = "match"
+)
+
+// FileType of file entry to be matched by search
+type FileType string
+
+const (
+	// TypeFile matches file entries which are not directories
+	TypeFile FileType = "file"
+
+	// TypeDir matches directories
+	TypeDir FileType = "dir"
+)
+
+const (
+	// DefaultLimit is default maximum number of hits returned for search
+	DefaultLimit = 10
+
+	// DefaultFuzziness is default edit distance for fuzzy query
+	DefaultFuzziness = 1
+)
+
+// SearchOptions for options on searching term, DefaultSearchOptions should be used as starting point
+type SearchOptions struct {
+	// Limit is maximum number of hits to be returned
+	Limit int `json:"limit"`
+
+	// Offset is number of hits to be skipped from the start
+	Offset int `json:"offset"`
+
+	// Fuzziness is edit distance for fuzzy query
+	Fuzziness int `json:"fuzziness"`
+
+	// Queries are kinds of queries to be combined as disjunction for searching term
+	Queries []QueryKind `json:"queries"`
+
+	// Boosts of each kind of queries, queries without boost will be boosted with 1
+	Boosts map[QueryKind]float64 `json:"boosts"`
+
+	// FieldBoosts of fields searched by each kind of queries, which are multiplied with boost of query kind
+	// Only path is searched if not specified, while query string is always parsed against path
+	FieldBoosts map[string]float64 `json:"fieldBoosts"`
+
+	// PathBonus is weight of bonuses for paths with exact basename match, match at segment start and shallower depth
+	// Bonuses will not be applied if it is 0 or sort order is specified
+	PathBonus float64 `json:"pathBonus"`
+
+	// Sort order of hits with field names, prefixed with "-" for descending order, i.e. "-_score", "-modTime"
+	// Hits will be sorted by descending score if not specified, and hits of the same order are sorted by path
+	Sort []string `json:"sort"`
+
+	// Filters of field names with values, hits must exactly match each of them, i.e. {"ext": "pdf"}
+	Filters map[string]string `json:"filters"`
+
+	// Type of hits to be matched, hits of any type are matched if not specified
+	Type FileType `json:"type"`
+
+	// Exts are extensions without leading dot, hits must match any of them if specified, i.e. ["pdf", "docx"]
+	Exts []string `json:"exts"`
+
+	// In are absolute paths of directories, hits must be within any of them if specified
+	In []string `json:"in"`
+
+	// ModifiedAfter and ModifiedBefore bound modification time of hits inclusively, which are unbounded if zero
+	ModifiedAfter  time.Time `json:"modifiedAfter"`
+	ModifiedBefore time.Time `json:"modifiedBefore"`
+
+	// Fields are stored fields of Document to be loaded for hits, "*" for all fields
+	Fields []string `json:"fields"`
+
+	// Highlight requests fragments of path with matched terms, and ranges of path matched by the term for hits
+	Highlight bool `json:"highlight"`
+
+	// FrecencyWeight is weight of frecency score of selections blended with text score, ranges from 0 to 1
+	// Frecency will not be used if it is 0 or sort order is specified
+	FrecencyWeight float64 `json:"frecencyWeight"`
+}
+
+// DefaultSearchOptions returns options with disjunction of all kinds of queries
+func DefaultSearchOptions() SearchOptions {
+	return SearchOptions{
+		Limit:     DefaultLimit,
+		Fuzziness: DefaultFuzziness,
+		Queries:   []QueryKind{Fuzzy, Prefix, QueryString, Wildcard, Match},
+		Boosts: map[QueryKind]float64{
+			Fuzzy:    2,
+			Prefix:   2,
+			Wildcard: 2,
+			Match:    5,
+		},
+		FieldBoosts: map[string]float64{
+			FieldName:     3,
+			FieldSegments: 1,
+			FieldPath:     1,
+		},
+		PathBonus:      DefaultPathBonus,
+		FrecencyWeight: DefaultFrecencyWeight,
+	}
+}
+
+func newSearchRequest(term string, opts SearchOptions, analyzer AnalyzerOptions) (*bleve.SearchRequest, error) {
+	if opts.Limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive: %v", opts.Limit)
+	}
+	if opts.Offset < 0 {
+		return nil, fmt.Errorf("offset cannot be negative: %v", opts.Offset)
+	}
+	if opts.Fuzziness < 0 {
+		return nil, fmt.Errorf("fuzziness cannot be negative: %v", opts.Fuzziness)
+	}
+	if opts.PathBonus < 0 {
+		return nil, fmt.Errorf("path bonus cannot be negative: %v", opts.PathBonus)
+	}
+	if opts.FrecencyWeight < 0 || opts.FrecencyWeight > 1 {
+		return nil, fmt.Errorf("frecency weight must be between 0 and 1: %v", opts.FrecencyWeight)
+	}
+	if len(opts.Queries) == 0 {
+		return nil, fmt.Errorf("at least one query kind is required")
+	}
+	if opts.Type != "" && opts.Type != TypeFile && opts.Type != TypeDir {
+		return nil, fmt.Errorf("\"%v\" type is not supported", opts.Type)
+	}
+
+	var q query.Query
+	if strings.TrimSpace(term) == "" {
+		// term could be blank if only filters are specified
+		q = bleve.NewMatchAllQuery()
+	} else {
+		var queries []query.Query
+		for _, kind := range opts.Queries {
+			q, err := newFieldsQuery(kind, term, opts, analyzer)
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, q)
+		}
+		q = bleve.NewDisjunctionQuery(queries...)
+	}
+
+	filters := newFilterQueries(opts)
+	if len(filters) != 0 {
+		q = bleve.NewConjunctionQuery(append([]query.Query{q}, filters...)...)
+	}
+
+	req := bleve.NewSearchRequestOptions(q, opts.Limit, opts.Offset, false)
+	req.Fields = opts.Fields
+	if opts.Highlight {
+		req.Highlight = bleve.NewHighlight()
+		req.Highlight.AddField(FieldPath)
+		req.IncludeLocations = true
+	}
+	req.SortBy(sortOrder(opts.Sort))
+	return req, nil
+}
+
+// newFieldsQuery of kind as disjunction across fields with their boosts, or query of default field if field boosts are not specified
+// Boost of disjunction is not applied by bleve, so boost of query kind is multiplied into query of each field instead
+func newFieldsQuery(kind QueryKind, term string, opts SearchOptions, analyzer AnalyzerOptions) (query.Query, error) {
+	boost, ok := opts.Boosts[kind]
+	if !ok {
+		boost = 1
+	}
+	if kind == QueryString || len(opts.FieldBoosts) == 0 {
+		q, err := newQuery(kind, term, "", opts, analyzer)
+		if err != nil {
+			return nil, err
+		}
+		q.SetBoost(boost)
+		return q, nil
+	}
+
+	// fields are sorted such that query is the same for the same options
+	fields := make([]string, 0, len(opts.FieldBoosts))
+	for field := range opts.FieldBoosts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var queries []query.Query
+	for _, field := range fields {
+		fieldBoost := opts.FieldBoosts[field]
+		if fieldBoost <= 0 {
+			continue
+		}
+		q, err := newQuery(kind, term, field, opts, analyzer)
+		if err != nil {
+			return nil, err
+		}
+		q.SetBoost(boost * fieldBoost)
+		queries = append(queries, q)
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("at least one field with positive boost is required")
+	}
+	return bleve.NewDisjunctionQuery(queries...), nil
+}
+
+// sortOrder of hits with path as the last tie breaker, such that hits of the same score are paged stably
+func sortOrder(order []string) []string {
+	if len(order) == 0 {
+		order = []string{"-_score"}
+	}
+	for _, field := range order {
+		if field == "_id" || field == "-_id" {
+			return order
+		}
+	}
+	return append(append([]string(nil), order...), "_id")
+}
+
+// sortHits by descending score, and then by path for hits of the same score
+func sortHits(hits search.DocumentMatchCollection) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+}
+
+// HasFilters checks if any of filters is specified, such that blank term could match all paths with filters
+func (o SearchOptions) HasFilters() bool {
+	return len(o.Filters) != 0 || o.Type != "" || len(o.Exts) != 0 || len(o.In) != 0 ||
+		!o.ModifiedAfter.IsZero() || !o.ModifiedBefore.IsZero()
+}
+
+// newFilterQueries of options, which hits must match all of them
+func newFilterQueries(opts SearchOptions) []query.Query {
+	var filters []query.Query
+	for field, value := range opts.Filters {
+		filter := bleve.NewTermQuery(value)
+		filter.SetField(field)
+		filters = append(filters, filter)
+	}
+	if opts.Type != "" {
+		filter := bleve.NewBoolFieldQuery(opts.Type == TypeDir)
+		filter.SetField(FieldIsDir)
+		filters = append(filters, filter)
+	}
+	if len(opts.Exts) != 0 {
+		var exts []query.Query
+		for _, ext := range opts.Exts {
+			q := bleve.NewTermQuery(strings.ToLower(strings.TrimPrefix(ext, ".")))
+			q.SetField(FieldExt)
+			exts = append(exts, q)
+		}
+		filters = append(filters, bleve.NewDisjunctionQuery(exts...))
+	}
+	if len(opts.In) != 0 {
+		// hits within directory have it as parent directory, or parent directory prefixed with it
+		var dirs []query.Query
+		for _, dir := range opts.In {
+			dir = filepath.Clean(dir)
+			parent := bleve.NewTermQuery(dir)
+			parent.SetField(FieldDir)
+			ancestor := bleve.NewPrefixQuery(strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator))
+			ancestor.SetField(FieldDir)
+			dirs = append(dirs, parent, ancestor)
+		}
+		filters = append(filters, bleve.NewDisjunctionQuery(dirs...))
+	}
+	if !opts.ModifiedAfter.IsZero() || !opts.ModifiedBefore.IsZero() {
+		inclusive := true
+		filter := bleve.NewDateRangeInclusiveQuery(opts.ModifiedAfter, opts.ModifiedBefore, &inclusive, &inclusive)
+		filter.SetField(FieldModTime)
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+type fieldableQuery interface {
+	query.BoostableQuery
+	query.FieldableQuery
+}
+
+// newQuery of kind on field, where term of queries not analyzed is normalized the same as tokens by analyzer
+// Default field is searched if field is empty
+func newQuery(kind QueryKind, term string, field string, opts SearchOptions, analyzer AnalyzerOptions) (query.BoostableQuery, error) {
+	var q fieldableQuery
+	switch kind {
+	case Fuzzy:
+		fuzzy := bleve.NewFuzzyQuery(analyzer.normalize(term))
+		fuzzy.SetFuzziness(opts.Fuzziness)
+		q = fuzzy
+	case Prefix:
+		q = bleve.NewPrefixQuery(analyzer.normalize(term))
+	case QueryString:
+		return bleve.NewQueryStringQuery(term), nil
+	case Wildcard:
+		q = bleve.NewWildcardQuery(analyzer.normalize(term))
+	case Match:
+		q = bleve.NewMatchQuery(term)
+	default:
+		return nil, fmt.Errorf("\"%v\" query is not supported", kind)
+	}
+	if field != "" {
+		q.SetField(field)
+	}
+	return q, nil
+}
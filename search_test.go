@@ -0,0 +1,185 @@
+package fzd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSearchRequestWithDefaultOptions(t *testing.T) {
+	req, err := newSearchRequest("test", DefaultSearchOptions(), AnalyzerOptions{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, DefaultLimit, req.Size)
+	assert.Equal(t, 0, req.From)
+
+	disjunction, ok := req.Query.(*query.DisjunctionQuery)
+	assert.True(t, ok)
+	assert.Equal(t, 5, len(disjunction.Disjuncts))
+
+	// each kind of queries is searched across fields sorted by name, with boosts multiplied
+	fuzzyFields, ok := disjunction.Disjuncts[0].(*query.DisjunctionQuery)
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(fuzzyFields.Disjuncts))
+
+	fuzzy, ok := fuzzyFields.Disjuncts[0].(*query.FuzzyQuery)
+	assert.True(t, ok)
+	assert.Equal(t, FieldName, fuzzy.Field())
+	assert.Equal(t, DefaultFuzziness, fuzzy.Fuzziness)
+	assert.Equal(t, 6.0, fuzzy.Boost())
+
+	queryString, ok := disjunction.Disjuncts[2].(*query.QueryStringQuery)
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, queryString.Boost())
+
+	matchFields, ok := disjunction.Disjuncts[4].(*query.DisjunctionQuery)
+	assert.True(t, ok)
+	match, ok := matchFields.Disjuncts[2].(*query.MatchQuery)
+	assert.True(t, ok)
+	assert.Equal(t, FieldSegments, match.Field())
+	assert.Equal(t, 5.0, match.Boost())
+}
+
+func TestNewSearchRequestWithoutFieldBoosts(t *testing.T) {
+	opts := DefaultSearchOptions()
+	opts.FieldBoosts = nil
+
+	req, err := newSearchRequest("test", opts, AnalyzerOptions{})
+	assert.NoError(t, err)
+
+	disjunction, ok := req.Query.(*query.DisjunctionQuery)
+	assert.True(t, ok)
+	fuzzy, ok := disjunction.Disjuncts[0].(*query.FuzzyQuery)
+	assert.True(t, ok)
+	assert.Equal(t, "", fuzzy.Field())
+	assert.Equal(t, 2.0, fuzzy.Boost())
+}
+
+func TestNewSearchRequestWithOptions(t *testing.T) {
+	opts := DefaultSearchOptions()
+	opts.Limit = 50
+	opts.Offset = 10
+	opts.Fuzziness = 2
+	opts.Queries = []QueryKind{Fuzzy}
+	opts.Boosts = map[QueryKind]float64{Fuzzy: 3}
+	opts.Sort = []string{"-" + FieldModTime}
+	opts.Filters = map[string]string{FieldExt: "pdf"}
+
+	req, err := newSearchRequest("test", opts, AnalyzerOptions{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 50, req.Size)
+	assert.Equal(t, 10, req.From)
+	assert.Equal(t, 2, len(req.Sort))
+
+	conjunction, ok := req.Query.(*query.ConjunctionQuery)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(conjunction.Conjuncts))
+
+	disjunction, ok := conjunction.Conjuncts[0].(*query.DisjunctionQuery)
+	assert.True(t, ok)
+	fuzzyFields, ok := disjunction.Disjuncts[0].(*query.DisjunctionQuery)
+	assert.True(t, ok)
+	fuzzy, ok := fuzzyFields.Disjuncts[1].(*query.FuzzyQuery)
+	assert.True(t, ok)
+	assert.Equal(t, FieldPath, fuzzy.Field())
+	assert.Equal(t, 2, fuzzy.Fuzziness)
+	assert.Equal(t, 3.0, fuzzy.Boost())
+
+	filter, ok := conjunction.Conjuncts[1].(*query.TermQuery)
+	assert.True(t, ok)
+	assert.Equal(t, FieldExt, filter.Field())
+	assert.Equal(t, "pdf", filter.Term)
+}
+
+func TestNewSearchRequestWithInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*SearchOptions)
+		err    string
+	}{
+		{"zero limit", func(o *SearchOptions) { o.Limit = 0 }, "limit must be positive: 0"},
+		{"negative offset", func(o *SearchOptions) { o.Offset = -1 }, "offset cannot be negative: -1"},
+		{"negative fuzziness", func(o *SearchOptions) { o.Fuzziness = -1 }, "fuzziness cannot be negative: -1"},
+		{"unknown type", func(o *SearchOptions) { o.Type = "link" }, "\"link\" type is not supported"},
+		{"negative path bonus", func(o *SearchOptions) { o.PathBonus = -1 }, "path bonus cannot be negative: -1"},
+		{"no positive field boost", func(o *SearchOptions) { o.FieldBoosts = map[string]float64{FieldName: 0} }, "at least one field with positive boost is required"},
+		{"no queries", func(o *SearchOptions) { o.Queries = nil }, "at least one query kind is required"},
+		{"unknown query", func(o *SearchOptions) { o.Queries = []QueryKind{"unknown"} }, "\"unknown\" query is not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultSearchOptions()
+			tt.modify(&opts)
+			_, err := newSearchRequest("test", opts, AnalyzerOptions{})
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestNewSearchRequestWithFilters(t *testing.T) {
+	opts := DefaultSearchOptions()
+	opts.Type = TypeFile
+	opts.Exts = []string{".PDF", "docx"}
+	opts.In = []string{"/home/Projects"}
+	opts.ModifiedAfter = time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	req, err := newSearchRequest("", opts, AnalyzerOptions{})
+	assert.NoError(t, err)
+
+	conjunction, ok := req.Query.(*query.ConjunctionQuery)
+	assert.True(t, ok)
+	assert.Equal(t, 5, len(conjunction.Conjuncts))
+
+	_, ok = conjunction.Conjuncts[0].(*query.MatchAllQuery)
+	assert.True(t, ok)
+
+	isDir, ok := conjunction.Conjuncts[1].(*query.BoolFieldQuery)
+	assert.True(t, ok)
+	assert.Equal(t, FieldIsDir, isDir.Field())
+	assert.False(t, isDir.Bool)
+
+	exts, ok := conjunction.Conjuncts[2].(*query.DisjunctionQuery)
+	assert.True(t, ok)
+	pdf, ok := exts.Disjuncts[0].(*query.TermQuery)
+	assert.True(t, ok)
+	assert.Equal(t, "pdf", pdf.Term)
+
+	in, ok := conjunction.Conjuncts[3].(*query.DisjunctionQuery)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(in.Disjuncts))
+
+	modTime, ok := conjunction.Conjuncts[4].(*query.DateRangeQuery)
+	assert.True(t, ok)
+	assert.Equal(t, FieldModTime, modTime.Field())
+	assert.True(t, modTime.End.IsZero())
+}
+
+func TestHasFilters(t *testing.T) {
+	opts := DefaultSearchOptions()
+	assert.False(t, opts.HasFilters())
+	opts.Exts = []string{"pdf"}
+	assert.True(t, opts.HasFilters())
+	opts = DefaultSearchOptions()
+	opts.ModifiedBefore = time.Now()
+	assert.True(t, opts.HasFilters())
+}
+
+func TestSortOrder(t *testing.T) {
+	assert.Equal(t, []string{"-_score", "_id"}, sortOrder(nil))
+	assert.Equal(t, []string{"-modTime", "_id"}, sortOrder([]string{"-modTime"}))
+	assert.Equal(t, []string{"-_id", "name"}, sortOrder([]string{"-_id", "name"}))
+}
+
+func TestSortHitsByPathForSameScore(t *testing.T) {
+	res := newSearchResult(map[string]float64{"c": 1, "b": 2, "a": 1}, "c", "b", "a")
+	sortHits(res.Hits)
+
+	var ids []string
+	for _, h := range res.Hits {
+		ids = append(ids, h.ID)
+	}
+	assert.Equal(t, []string{"b", "a", "c"}, ids)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/horacehylee/fzd"
+)
+
+// Client of fzd server
+type Client struct {
+	http    *http.Client
+	baseURL string
+}
+
+// NewClient for server listening on specified network and address, network could be "unix" or "tcp"
+func NewClient(network string, address string) *Client {
+	if network == "unix" {
+		dialer := &net.Dialer{}
+		return &Client{
+			http: &http.Client{
+				Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						return dialer.DialContext(ctx, network, address)
+					},
+				},
+			},
+			// host is not used, as requests are always dialed to the Unix socket
+			baseURL: "http://fzd",
+		}
+	}
+	return &Client{
+		http:    &http.Client{},
+		baseURL: "http://" + address,
+	}
+}
+
+// Ping checks if server is reachable and index is opened within specified timeout
+func (c *Client) Ping(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var res StatusResponse
+	return c.do(ctx, http.MethodGet, "/status", nil, &res)
+}
+
+// Search term with options
+func (c *Client) Search(term string, opts fzd.SearchOptions) (*SearchResponse, error) {
+	var res SearchResponse
+	err := c.do(context.Background(), http.MethodPost, "/search", SearchRequest{Term: term, Options: opts}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Status of currently opened index
+func (c *Client) Status() (*StatusResponse, error) {
+	var res StatusResponse
+	err := c.do(context.Background(), http.MethodGet, "/status", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Reindex incrementally, or rebuild index from scratch if full is specified
+func (c *Client) Reindex(full bool) (*ReindexResponse, error) {
+	var res ReindexResponse
+	err := c.do(context.Background(), http.MethodPost, "/reindex", ReindexRequest{Full: full}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Index rebuilds sub-indexes of specified locations from scratch, or all of them if not specified
+func (c *Client) Index(locations ...string) (*ReindexResponse, error) {
+	var res ReindexResponse
+	err := c.do(context.Background(), http.MethodPost, "/reindex", ReindexRequest{Full: true, Locations: locations}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Generations of index kept within base path, sorted from the newest created
+func (c *Client) Generations() ([]fzd.Generation, error) {
+	var res GenerationsResponse
+	err := c.do(context.Background(), http.MethodGet, "/generations", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res.Generations, nil
+}
+
+// Rollback to specified generation, or the previous generation if name is empty
+func (c *Client) Rollback(name string) (*RollbackResponse, error) {
+	var res RollbackResponse
+	err := c.do(context.Background(), http.MethodPost, "/rollback", RollbackRequest{Name: name}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DocCount returns number of documents stored within the index
+func (c *Client) DocCount() (uint64, error) {
+	var res CountResponse
+	err := c.do(context.Background(), http.MethodGet, "/count", nil, &res)
+	if err != nil {
+		return 0, err
+	}
+	return res.Count, nil
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body interface{}, v interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&buf).Encode(body)
+		if err != nil {
+			return fmt.Errorf("failed to serialize request: %w", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e ErrorResponse
+		err = json.NewDecoder(resp.Body).Decode(&e)
+		if err != nil || e.Error == "" {
+			return fmt.Errorf("server responded %v", resp.Status)
+		}
+		return fmt.Errorf("server responded %v: %v", resp.Status, e.Error)
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,2 @@
+// Server package for fzd, which exposes opened fzd.Indexer with HTTP/JSON API over Unix socket or TCP, and client for it
+package server
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/horacehylee/fzd"
+)
+
+const (
+	// SocketFileName is the default Unix socket file within index base path
+	SocketFileName = "fzd.sock"
+)
+
+// SearchRequest of searching term with options
+type SearchRequest struct {
+	Term    string            `json:"term"`
+	Options fzd.SearchOptions `json:"options"`
+}
+
+// SearchResponse of search hits and total number of hits
+type SearchResponse struct {
+	Total uint64    `json:"total"`
+	Hits  []fzd.Hit `json:"hits"`
+}
+
+// StatusResponse of currently opened index
+type StatusResponse struct {
+	IndexName   string    `json:"indexName"`
+	LastIndexed time.Time `json:"lastIndexed"`
+	DocCount    uint64    `json:"docCount"`
+}
+
+// ReindexRequest for incremental reindex, or rebuilding index from scratch if full is specified
+// Only sub-indexes of locations are rebuilt from scratch if specified, where full is implied
+type ReindexRequest struct {
+	Full      bool     `json:"full"`
+	Locations []string `json:"locations,omitempty"`
+}
+
+// ReindexResponse of reindexed index, where delta is only available for incremental reindex
+type ReindexResponse struct {
+	Full      bool             `json:"full"`
+	Delta     fzd.Delta        `json:"delta"`
+	Report    *fzd.IndexReport `json:"report"`
+	IndexName string           `json:"indexName"`
+	DocCount  uint64           `json:"docCount"`
+}
+
+// GenerationsResponse of index generations kept within base path, sorted from the newest created
+type GenerationsResponse struct {
+	Generations []fzd.Generation `json:"generations"`
+}
+
+// RollbackRequest for swapping to specified generation, or the previous generation if name is empty
+type RollbackRequest struct {
+	Name string `json:"name"`
+}
+
+// RollbackResponse of index rolled back to
+type RollbackResponse struct {
+	IndexName string `json:"indexName"`
+	DocCount  uint64 `json:"docCount"`
+}
+
+// CountResponse of number of documents within the index
+type CountResponse struct {
+	Count uint64 `json:"count"`
+}
+
+// ErrorResponse of failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type handler struct {
+	indexer *fzd.Indexer
+	mux     *http.ServeMux
+}
+
+// NewHandler serving opened indexer with following endpoints:
+//
+//	POST /search      searches with SearchRequest, and responds SearchResponse
+//	GET  /status      responds StatusResponse
+//	POST /reindex     reindexes with ReindexRequest, and responds ReindexResponse
+//	GET  /count       responds CountResponse
+//	GET  /generations responds GenerationsResponse
+//	POST /rollback    rolls back with RollbackRequest, and responds RollbackResponse
+func NewHandler(indexer *fzd.Indexer) http.Handler {
+	h := &handler{
+		indexer: indexer,
+		mux:     http.NewServeMux(),
+	}
+	h.mux.HandleFunc("/search", h.search)
+	h.mux.HandleFunc("/status", h.status)
+	h.mux.HandleFunc("/reindex", h.reindex)
+	h.mux.HandleFunc("/count", h.count)
+	h.mux.HandleFunc("/generations", h.generations)
+	h.mux.HandleFunc("/rollback", h.rollback)
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *handler) search(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req SearchRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid search request: %w", err))
+		return
+	}
+	// term is left blank for filters only, which matches all paths with filters
+	if strings.TrimSpace(req.Term) == "" && !req.Options.HasFilters() {
+		writeError(w, http.StatusBadRequest, errors.New("term cannot be blank without filters"))
+		return
+	}
+	res, err := h.indexer.SearchWithOptions(req.Term, req.Options)
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SearchResponse{
+		Total: res.Total,
+		Hits:  fzd.NewHits(res),
+	})
+}
+
+func (h *handler) status(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	name, err := h.indexer.IndexName()
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	t, err := h.indexer.LastIndexed()
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	count, err := h.indexer.DocCount()
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, StatusResponse{
+		IndexName:   name,
+		LastIndexed: t,
+		DocCount:    count,
+	})
+}
+
+func (h *handler) reindex(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req ReindexRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid reindex request: %w", err))
+		return
+	}
+
+	res := ReindexResponse{Full: req.Full || len(req.Locations) > 0}
+	if !res.Full {
+		res.Delta, res.Report, err = h.indexer.ReindexContext(r.Context(), fzd.IndexOptions{})
+		if errors.Is(err, fzd.ErrIndexManifestDoesNotExist) {
+			// index created without manifest could only be rebuilt from scratch
+			res.Full = true
+		} else if err != nil {
+			writeIndexerError(w, err)
+			return
+		}
+	}
+	if res.Full {
+		var name string
+		name, res.Report, err = h.indexer.IndexContext(r.Context(), fzd.IndexOptions{Locations: req.Locations})
+		if err != nil {
+			writeIndexerError(w, err)
+			return
+		}
+		// searches are not interrupted, as index is swapped atomically
+		err = h.indexer.OpenAndSwap(name)
+		if err != nil {
+			writeIndexerError(w, err)
+			return
+		}
+	}
+
+	res.IndexName, err = h.indexer.IndexName()
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	res.DocCount, err = h.indexer.DocCount()
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *handler) count(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	count, err := h.indexer.DocCount()
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, CountResponse{Count: count})
+}
+
+func (h *handler) generations(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	gens, err := h.indexer.Generations()
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, GenerationsResponse{Generations: gens})
+}
+
+func (h *handler) rollback(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req RollbackRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid rollback request: %w", err))
+		return
+	}
+
+	var res RollbackResponse
+	res.IndexName, err = h.indexer.Rollback(req.Name)
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	res.DocCount, err = h.indexer.DocCount()
+	if err != nil {
+		writeIndexerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v is not allowed", r.Method))
+	return false
+}
+
+func writeIndexerError(w http.ResponseWriter, err error) {
+	if errors.Is(err, fzd.ErrIndexNotOpened) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if errors.Is(err, fzd.ErrGenerationDoesNotExist) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, fzd.ErrLocationNotConfigured) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, fzd.ErrIndexLocked) || errors.Is(err, fzd.ErrNoPreviousGeneration) ||
+		errors.Is(err, fzd.ErrGenerationModified) || errors.Is(err, fzd.ErrIndexIncompatible) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, ErrorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,259 @@
+package server_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horacehylee/fzd"
+	"github.com/horacehylee/fzd/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// fileMode is the default file mode for creating temp directory using os.MkdirTemp
+const fileMode = 0700
+
+type ServerTestSuite struct {
+	suite.Suite
+	dir        string
+	file       string
+	indexesDir string
+	indexer    *fzd.Indexer
+	server     *httptest.Server
+	client     *server.Client
+}
+
+func TestServerTestSuite(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
+
+func (suite *ServerTestSuite) SetupTest() {
+	t := suite.T()
+
+	dir, err := os.MkdirTemp("", "testServer")
+	assert.NoError(t, err)
+	suite.dir = dir
+
+	suite.file = filepath.Join(dir, "served.txt")
+	err = os.WriteFile(suite.file, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	indexesDir, err := os.MkdirTemp("", "testServerIndexes")
+	assert.NoError(t, err)
+	suite.indexesDir = indexesDir
+
+	indexer, err := fzd.NewIndexer(indexesDir, fzd.WithLocation(dir, fzd.LocationOption{}))
+	assert.NoError(t, err)
+	suite.indexer = indexer
+
+	name, err := indexer.Index()
+	assert.NoError(t, err)
+	err = indexer.OpenAndSwap(name)
+	assert.NoError(t, err)
+
+	suite.server = httptest.NewServer(server.NewHandler(indexer))
+	suite.client = server.NewClient("tcp", strings.TrimPrefix(suite.server.URL, "http://"))
+}
+
+func (suite *ServerTestSuite) TearDownTest() {
+	t := suite.T()
+
+	suite.server.Close()
+	assert.NoError(t, suite.indexer.Close())
+	assert.NoError(t, os.RemoveAll(suite.dir))
+	assert.NoError(t, os.RemoveAll(suite.indexesDir))
+}
+
+func (suite *ServerTestSuite) TestSearch() {
+	t := suite.T()
+
+	opts := fzd.DefaultSearchOptions()
+	opts.Fields = []string{"*"}
+	res, err := suite.client.Search("served", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), res.Total)
+	if assert.Len(t, res.Hits, 1) {
+		assert.Equal(t, suite.file, res.Hits[0].Path)
+		assert.Equal(t, "served.txt", res.Hits[0].Name)
+		assert.Equal(t, suite.dir, res.Hits[0].Location)
+	}
+}
+
+func (suite *ServerTestSuite) TestSearchWithInvalidOptions() {
+	t := suite.T()
+
+	_, err := suite.client.Search("served", fzd.SearchOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "500")
+
+	_, err = suite.client.Search("", fzd.DefaultSearchOptions())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "term cannot be blank without filters")
+}
+
+func (suite *ServerTestSuite) TestSearchWithFiltersOnly() {
+	t := suite.T()
+
+	opts := fzd.DefaultSearchOptions()
+	opts.Type = fzd.TypeFile
+	res, err := suite.client.Search("", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), res.Total)
+	if assert.Len(t, res.Hits, 1) {
+		assert.Equal(t, suite.file, res.Hits[0].Path)
+	}
+}
+
+func (suite *ServerTestSuite) TestStatusAndCount() {
+	t := suite.T()
+
+	name, err := suite.indexer.IndexName()
+	assert.NoError(t, err)
+
+	status, err := suite.client.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, name, status.IndexName)
+	assert.Equal(t, uint64(2), status.DocCount)
+	assert.False(t, status.LastIndexed.IsZero())
+
+	count, err := suite.client.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+}
+
+func (suite *ServerTestSuite) TestReindex() {
+	t := suite.T()
+
+	added := filepath.Join(suite.dir, "added.txt")
+	err := os.WriteFile(added, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	res, err := suite.client.Reindex(false)
+	assert.NoError(t, err)
+	assert.False(t, res.Full)
+	assert.Equal(t, 1, res.Delta.Added)
+	assert.Equal(t, uint64(3), res.DocCount)
+	if assert.NotNil(t, res.Report) {
+		assert.Equal(t, 3, res.Report.Paths())
+		assert.Empty(t, res.Report.Errors)
+	}
+
+	search, err := suite.client.Search("added", fzd.DefaultSearchOptions())
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, search.Hits) {
+		assert.Equal(t, added, search.Hits[0].Path)
+	}
+}
+
+func (suite *ServerTestSuite) TestReindexFull() {
+	t := suite.T()
+
+	prev, err := suite.indexer.IndexName()
+	assert.NoError(t, err)
+
+	res, err := suite.client.Reindex(true)
+	assert.NoError(t, err)
+	assert.True(t, res.Full)
+	assert.NotEqual(t, prev, res.IndexName)
+	assert.Equal(t, uint64(2), res.DocCount)
+}
+
+func (suite *ServerTestSuite) TestIndexLocations() {
+	t := suite.T()
+
+	prev, err := suite.indexer.IndexName()
+	assert.NoError(t, err)
+
+	res, err := suite.client.Index(suite.dir)
+	assert.NoError(t, err)
+	assert.True(t, res.Full)
+	assert.NotEqual(t, prev, res.IndexName)
+	assert.Equal(t, uint64(2), res.DocCount)
+
+	_, err = suite.client.Index(filepath.Join(suite.dir, "missing"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "400")
+	}
+}
+
+func (suite *ServerTestSuite) TestGenerationsAndRollback() {
+	t := suite.T()
+
+	prev, err := suite.indexer.IndexName()
+	assert.NoError(t, err)
+	res, err := suite.client.Reindex(true)
+	assert.NoError(t, err)
+
+	gens, err := suite.client.Generations()
+	assert.NoError(t, err)
+	if assert.Len(t, gens, 2) {
+		assert.Equal(t, res.IndexName, gens[0].Name)
+		assert.True(t, gens[0].Current)
+		assert.Equal(t, prev, gens[1].Name)
+	}
+
+	rollback, err := suite.client.Rollback("")
+	assert.NoError(t, err)
+	assert.Equal(t, prev, rollback.IndexName)
+	assert.Equal(t, uint64(2), rollback.DocCount)
+
+	_, err = suite.client.Rollback("missing")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "404")
+	}
+}
+
+func (suite *ServerTestSuite) TestMethodNotAllowed() {
+	t := suite.T()
+
+	resp, err := http.Get(suite.server.URL + "/search")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, http.MethodPost, resp.Header.Get("Allow"))
+}
+
+func (suite *ServerTestSuite) TestIndexNotOpened() {
+	t := suite.T()
+
+	indexer, err := fzd.NewIndexer(suite.indexesDir)
+	assert.NoError(t, err)
+	s := httptest.NewServer(server.NewHandler(indexer))
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "/count")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func (suite *ServerTestSuite) TestUnixSocket() {
+	t := suite.T()
+
+	socket := filepath.Join(suite.indexesDir, server.SocketFileName)
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix socket is not supported: %v", err)
+	}
+	s := &http.Server{Handler: server.NewHandler(suite.indexer)}
+	go s.Serve(l)
+	defer s.Close()
+
+	client := server.NewClient("unix", socket)
+	assert.NoError(t, client.Ping(time.Second))
+	count, err := client.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+}
+
+func TestPingUnreachable(t *testing.T) {
+	socket := filepath.Join(os.TempDir(), "testServerNotExist.sock")
+	client := server.NewClient("unix", socket)
+	assert.Error(t, client.Ping(100*time.Millisecond))
+}
@@ -5,15 +5,18 @@ import (
 	"io/fs"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/horacehylee/fzd/walker"
 	"github.com/stretchr/testify/assert"
 )
 
 type mockFileinfo struct {
-	mode  fs.FileMode
-	name  string
-	isDir bool
+	mode    fs.FileMode
+	name    string
+	isDir   bool
+	size    int64
+	modTime time.Time
 }
 
 func (m *mockFileinfo) Mode() fs.FileMode {
@@ -28,6 +31,14 @@ func (m *mockFileinfo) IsDir() bool {
 	return m.isDir
 }
 
+func (m *mockFileinfo) Size() int64 {
+	return m.size
+}
+
+func (m *mockFileinfo) ModTime() time.Time {
+	return m.modTime
+}
+
 func newMockFileInfo(name string, mode fs.FileMode, isDir bool) *mockFileinfo {
 	return &mockFileinfo{
 		mode:  mode,
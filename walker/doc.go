@@ -1,2 +1,3 @@
 // Walker package for fzd, which wraps github.com/karrick/godirwalk package for more similar interface as filepath.Walk
+// WalkParallel and Pool walk directories concurrently with bounded number of workers
 package walker
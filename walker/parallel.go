@@ -0,0 +1,213 @@
+package walker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/karrick/godirwalk"
+)
+
+// Pool bounds number of directories being read concurrently, which is shared by all walks of the pool
+// Walks of the same pool could be run concurrently, i.e. one for each location
+type Pool struct {
+	concurrency int
+	sem         chan struct{}
+}
+
+// NewPool with maximum number of concurrent workers, where concurrency below 1 is treated as 1
+func NewPool(concurrency int) *Pool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Pool{
+		concurrency: concurrency,
+		sem:         make(chan struct{}, concurrency),
+	}
+}
+
+// WalkParallel walks the file tree rooted at the specified directory with a pool of specified concurrency
+func WalkParallel(root string, concurrency int, fn WalkFunc) error {
+	return NewPool(concurrency).Walk(root, fn)
+}
+
+// Walk walks the file tree rooted at the specified directory, where directories are read by workers of the pool
+// WalkFunc is called with the same semantics as Walk, except that it could be called concurrently and entries are not visited in lexical order
+// Directory is always visited before its entries, and its entries are skipped if WalkFunc returned SkipThis for it
+// First error other than SkipThis returned from WalkFunc stops the walk, and it is returned
+// Errors of entries and directories are routed to WalkFunc as Walk does
+// If concurrency of the pool is 1, it walks sequentially in lexical order as Walk does
+func (p *Pool) Walk(root string, fn WalkFunc) error {
+	if p.concurrency == 1 {
+		p.sem <- struct{}{}
+		defer func() { <-p.sem }()
+		return Walk(root, fn)
+	}
+
+	root = filepath.Clean(root)
+	de, err := godirwalk.NewDirent(root)
+	if err != nil {
+		return err
+	}
+	if !de.IsDir() {
+		return fmt.Errorf("cannot Walk non-directory: %v", root)
+	}
+	stat, err := os.Lstat(root)
+	rootEntry := &entry{Dirent: de, stat: stat}
+	p.sem <- struct{}{}
+	err = fn(root, rootEntry, err)
+	<-p.sem
+	if err != nil {
+		if isSkip(err) {
+			return nil
+		}
+		return err
+	}
+
+	w := &parallelWalk{
+		pool:  p,
+		fn:    fn,
+		queue: []dir{{path: root, entry: rootEntry}},
+	}
+	w.cond = sync.NewCond(&w.mutex)
+	w.pending = 1
+
+	var wg sync.WaitGroup
+	for n := 0; n < p.concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+	return w.err
+}
+
+// Synchronized wraps WalkFunc to be called by one goroutine at a time, for WalkFunc that is not concurrent-safe
+func Synchronized(mutex sync.Locker, fn WalkFunc) WalkFunc {
+	return func(path string, info FileInfo, err error) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return fn(path, info, err)
+	}
+}
+
+// dir queued to be read
+type dir struct {
+	path  string
+	entry *entry
+}
+
+// parallelWalk of a single file tree, where queued directories are read by workers
+type parallelWalk struct {
+	pool *Pool
+	fn   WalkFunc
+
+	mutex sync.Mutex
+	cond  *sync.Cond
+	queue []dir
+
+	// pending is number of directories queued or being read
+	pending int
+	err     error
+}
+
+func (w *parallelWalk) work() {
+	scratch := make([]byte, godirwalk.MinimumScratchBufferSize)
+	for {
+		d, ok := w.next()
+		if !ok {
+			return
+		}
+		w.pool.sem <- struct{}{}
+		err := w.readDir(d, scratch)
+		<-w.pool.sem
+		w.done(err)
+	}
+}
+
+// next dequeues directory to be read, or returns false if walk is completed or failed
+func (w *parallelWalk) next() (dir, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for len(w.queue) == 0 && w.pending > 0 && w.err == nil {
+		w.cond.Wait()
+	}
+	if w.err != nil || len(w.queue) == 0 {
+		return dir{}, false
+	}
+	// dequeue the last one for depth first traversal, such that queue would not grow as wide as the tree
+	d := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	return d, true
+}
+
+func (w *parallelWalk) push(d dir) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.queue = append(w.queue, d)
+	w.pending++
+	w.cond.Signal()
+}
+
+func (w *parallelWalk) done(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.pending--
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	if w.pending == 0 || w.err != nil {
+		w.cond.Broadcast()
+	}
+}
+
+func (w *parallelWalk) failed() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.err != nil
+}
+
+func (w *parallelWalk) readDir(d dir, scratch []byte) error {
+	dirents, err := godirwalk.ReadDirents(d.path, scratch)
+	if err != nil {
+		err = w.fn(d.path, d.entry, err)
+		if err == nil || isSkip(err) {
+			return nil
+		}
+		return err
+	}
+	for _, de := range dirents {
+		if w.failed() {
+			return nil
+		}
+		path := filepath.Join(d.path, de.Name())
+		stat, statErr := os.Lstat(path)
+		e := &entry{Dirent: de, stat: stat}
+		err = w.fn(path, e, statErr)
+		if err != nil {
+			if isSkip(err) {
+				continue
+			}
+			return err
+		}
+		if statErr != nil {
+			continue
+		}
+		// symbolic links are not followed, as Walk does
+		if de.IsDir() {
+			w.push(dir{path: path, entry: e})
+		}
+	}
+	return nil
+}
+
+func isSkip(err error) bool {
+	return errors.Is(err, SkipThis) || errors.Is(err, filepath.SkipDir)
+}
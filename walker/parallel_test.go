@@ -0,0 +1,224 @@
+package walker_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/horacehylee/fzd/walker"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTree creates synthetic tree with specified depth, where each directory has fanOut directories and files
+func newTree(tb testing.TB, depth int, fanOut int) string {
+	root, err := os.MkdirTemp("", "testTree")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var create func(dir string, depth int)
+	create = func(dir string, depth int) {
+		for n := 0; n < fanOut; n++ {
+			err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%v.txt", n)), []byte("content"), fileMode)
+			if err != nil {
+				tb.Fatal(err)
+			}
+			if depth == 0 {
+				continue
+			}
+			sub := filepath.Join(dir, fmt.Sprintf("dir%v", n))
+			err = os.Mkdir(sub, fileMode)
+			if err != nil {
+				tb.Fatal(err)
+			}
+			create(sub, depth-1)
+		}
+	}
+	create(root, depth)
+	return root
+}
+
+// collector of visited paths, which is concurrent-safe
+type collector struct {
+	mutex sync.Mutex
+	paths []string
+}
+
+func (c *collector) walkFunc(path string, info walker.FileInfo, err error) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.paths = append(c.paths, path)
+	return err
+}
+
+func TestWalkParallelVisitsSameAsWalk(t *testing.T) {
+	root := newTree(t, 3, 3)
+	defer os.RemoveAll(root)
+
+	var expected collector
+	err := walker.Walk(root, expected.walkFunc)
+	assert.NoError(t, err)
+
+	for _, concurrency := range []int{0, 1, 2, 8} {
+		var actual collector
+		err := walker.WalkParallel(root, concurrency, actual.walkFunc)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected.paths, actual.paths, "concurrency %v", concurrency)
+	}
+}
+
+func TestWalkParallelVisitsDirBeforeEntries(t *testing.T) {
+	root := newTree(t, 3, 3)
+	defer os.RemoveAll(root)
+
+	var visited sync.Map
+	err := walker.WalkParallel(root, 4, func(path string, info walker.FileInfo, err error) error {
+		if path != root {
+			_, ok := visited.Load(filepath.Dir(path))
+			assert.True(t, ok, "parent of %v is not visited", path)
+		}
+		visited.Store(path, true)
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestWalkParallelSkipThis(t *testing.T) {
+	root := newTree(t, 3, 3)
+	defer os.RemoveAll(root)
+
+	skipped := filepath.Join(root, "dir1")
+	var c collector
+	err := walker.WalkParallel(root, 4, walker.Chain(func(path string, info walker.FileInfo, err error) error {
+		if path == skipped {
+			return walker.SkipThis
+		}
+		return nil
+	}, c.walkFunc))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, c.paths)
+	for _, path := range c.paths {
+		assert.False(t, strings.HasPrefix(path, skipped), "%v should be skipped", path)
+	}
+}
+
+func TestWalkParallelSkipThisForRoot(t *testing.T) {
+	root := newTree(t, 1, 2)
+	defer os.RemoveAll(root)
+
+	var c collector
+	err := walker.WalkParallel(root, 4, walker.Chain(c.walkFunc, func(path string, info walker.FileInfo, err error) error {
+		return walker.SkipThis
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{root}, c.paths)
+}
+
+func TestWalkParallelStopsOnError(t *testing.T) {
+	root := newTree(t, 3, 3)
+	defer os.RemoveAll(root)
+
+	errFailed := errors.New("failed")
+	var count int32
+	err := walker.WalkParallel(root, 4, func(path string, info walker.FileInfo, err error) error {
+		if atomic.AddInt32(&count, 1) == 5 {
+			return errFailed
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, errFailed)
+}
+
+func TestWalkParallelFromFile(t *testing.T) {
+	root := newTree(t, 0, 1)
+	defer os.RemoveAll(root)
+
+	file := filepath.Join(root, "file0.txt")
+	err := walker.WalkParallel(file, 4, func(path string, info walker.FileInfo, err error) error {
+		return nil
+	})
+	assert.EqualError(t, err, fmt.Sprintf("cannot Walk non-directory: %v", file))
+}
+
+func TestPoolBoundsConcurrencyAcrossWalks(t *testing.T) {
+	roots := []string{newTree(t, 2, 4), newTree(t, 2, 4), newTree(t, 2, 4)}
+	for _, root := range roots {
+		defer os.RemoveAll(root)
+	}
+
+	const concurrency = 2
+	pool := walker.NewPool(concurrency)
+	var active, maxActive int32
+	fn := func(path string, info walker.FileInfo, err error) error {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, root := range roots {
+		wg.Add(1)
+		go func(root string) {
+			defer wg.Done()
+			assert.NoError(t, pool.Walk(root, fn))
+		}(root)
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxActive, int32(concurrency))
+}
+
+func TestSynchronized(t *testing.T) {
+	root := newTree(t, 2, 4)
+	defer os.RemoveAll(root)
+
+	var mutex sync.Mutex
+	visited := make(map[string]bool)
+	err := walker.WalkParallel(root, 4, walker.Synchronized(&mutex, func(path string, info walker.FileInfo, err error) error {
+		// map is not concurrent-safe, which would be detected with race detector if not synchronized
+		visited[path] = true
+		return nil
+	}))
+	assert.NoError(t, err)
+
+	var c collector
+	err = walker.Walk(root, c.walkFunc)
+	assert.NoError(t, err)
+	assert.Len(t, visited, len(c.paths))
+}
+
+func BenchmarkWalk(b *testing.B) {
+	root := newTree(b, 3, 10)
+	defer os.RemoveAll(root)
+
+	fn := func(path string, info walker.FileInfo, err error) error {
+		return err
+	}
+	b.Run("sequential", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			err := walker.Walk(root, fn)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	for _, concurrency := range []int{2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("parallel-%v", concurrency), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				err := walker.WalkParallel(root, concurrency, fn)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
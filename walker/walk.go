@@ -2,39 +2,102 @@ package walker
 
 import (
 	"io/fs"
+	"os"
+	"time"
 
 	"github.com/karrick/godirwalk"
 )
 
 // FileInfo is a subset of os.FileInfo interface
 type FileInfo interface {
-	Name() string      // base name of the file
-	Mode() fs.FileMode // file mode bits
-	IsDir() bool       // abbreviation for Mode().IsDir()
+	Name() string       // base name of the file
+	Mode() fs.FileMode  // file mode bits
+	IsDir() bool        // abbreviation for Mode().IsDir()
+	Size() int64        // length in bytes for regular files; system-dependent for others
+	ModTime() time.Time // modification time
 }
 
 // WalkFunc is the type of the function called by Walk to visit each file or directory, using own FileInfo interface
+// If err is not nil, it is the error of the path, i.e. failed to stat the entry or read the directory, and info could be nil
+// Returning nil or SkipThis for err skips the path, otherwise the walk stops with the returned error
 type WalkFunc func(path string, info FileInfo, err error) error
 
 // entry struct that implements own FileInfo interface, it acts as wrapper for godirwalk.Dirent
+// Stat data is from os.Lstat, as godirwalk.Dirent only contains file mode type
 type entry struct {
 	*godirwalk.Dirent
+	stat fs.FileInfo
 }
 
 func (e *entry) Mode() fs.FileMode {
 	return e.ModeType()
 }
 
+func (e *entry) Size() int64 {
+	if e.stat == nil {
+		return 0
+	}
+	return e.stat.Size()
+}
+
+func (e *entry) ModTime() time.Time {
+	if e.stat == nil {
+		return time.Time{}
+	}
+	return e.stat.ModTime()
+}
+
 // SkipThis is used as return value from WalkFunc to indicate skipping particular file or directory
 var SkipThis = godirwalk.SkipThis
 
 // Walk walks the file tree rooted at the specified directory
 // WalkFunc parameter will be called with specified directory path and each file/directory item within it
+// If the file/directory item could not be stat, WalkFunc will be called with the error
+// If the directory could not be read, WalkFunc will be called again for the directory with the error
+// Errors of the root are returned directly, as there is nothing to be walked
 func Walk(root string, fn WalkFunc) error {
-	return godirwalk.Walk(root, &godirwalk.Options{
+	// halt is the error from WalkFunc stopping the walk, as godirwalk returns the original error of directory instead
+	var halt error
+	err := godirwalk.Walk(root, &godirwalk.Options{
 		Callback: func(osPathName string, de *godirwalk.Dirent) error {
-			e := &entry{Dirent: de}
-			return fn(osPathName, e, nil)
+			stat, statErr := os.Lstat(osPathName)
+			err := fn(osPathName, &entry{Dirent: de, stat: stat}, statErr)
+			if isSkip(err) || (err == nil && statErr != nil) {
+				// godirwalk only checks SkipThis by equality
+				return SkipThis
+			}
+			if err != nil {
+				halt = err
+			}
+			return err
+		},
+		ErrorCallback: func(osPathName string, err error) godirwalk.ErrorAction {
+			if halt != nil {
+				return godirwalk.Halt
+			}
+			err = fn(osPathName, newEntry(osPathName), err)
+			if err == nil || isSkip(err) {
+				return godirwalk.SkipNode
+			}
+			halt = err
+			return godirwalk.Halt
 		},
 	})
+	if halt != nil {
+		return halt
+	}
+	return err
+}
+
+// newEntry of path, or nil if it could not be stat
+func newEntry(path string) FileInfo {
+	de, err := godirwalk.NewDirent(path)
+	if err != nil {
+		return nil
+	}
+	stat, err := os.Lstat(path)
+	if err != nil {
+		return nil
+	}
+	return &entry{Dirent: de, stat: stat}
 }
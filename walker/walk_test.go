@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/horacehylee/fzd/walker"
 	"github.com/karrick/godirwalk"
@@ -17,10 +18,12 @@ import (
 const fileMode = 0700
 
 type item struct {
-	path  string
-	name  string
-	mode  fs.FileMode
-	isDir bool
+	path    string
+	name    string
+	mode    fs.FileMode
+	isDir   bool
+	size    int64
+	modTime time.Time
 }
 
 type WalkTestSuite struct {
@@ -71,10 +74,12 @@ func (suite *WalkTestSuite) SetupTest() {
 	suite.visited = make([]item, 0)
 	suite.visitedWalkFunc = func(path string, info walker.FileInfo, err error) error {
 		suite.visited = append(suite.visited, item{
-			path:  path,
-			name:  info.Name(),
-			mode:  info.Mode(),
-			isDir: info.IsDir(),
+			path:    path,
+			name:    info.Name(),
+			mode:    info.Mode(),
+			isDir:   info.IsDir(),
+			size:    info.Size(),
+			modTime: info.ModTime(),
 		})
 		return nil
 	}
@@ -93,11 +98,16 @@ func newItem(t *testing.T, path string, isDir bool) item {
 	de, err := godirwalk.NewDirent(path)
 	assert.NoError(t, err)
 
+	stat, err := os.Lstat(path)
+	assert.NoError(t, err)
+
 	return item{
-		path:  path,
-		name:  filepath.Base(path),
-		mode:  de.ModeType(), // godirwalk returnes slightly different fs.FileMode than os.Stat
-		isDir: isDir,
+		path:    path,
+		name:    filepath.Base(path),
+		mode:    de.ModeType(), // godirwalk returnes slightly different fs.FileMode than os.Stat
+		isDir:   isDir,
+		size:    stat.Size(),
+		modTime: stat.ModTime(),
 	}
 }
 
@@ -152,10 +162,12 @@ func (suite *WalkTestSuite) TestWalkNoErrorReturnedIfSkipThis() {
 	root := suite.level0Dir
 	fn := func(path string, info walker.FileInfo, err error) error {
 		suite.visited = append(suite.visited, item{
-			path:  path,
-			name:  info.Name(),
-			mode:  info.Mode(),
-			isDir: info.IsDir(),
+			path:    path,
+			name:    info.Name(),
+			mode:    info.Mode(),
+			isDir:   info.IsDir(),
+			size:    info.Size(),
+			modTime: info.ModTime(),
 		})
 		if path == suite.level2Dir {
 			return walker.SkipThis
@@ -180,10 +192,12 @@ func (suite *WalkTestSuite) TestWalkNoErrorReturnedIfSkipThisForRoot() {
 	root := suite.level0Dir
 	fn := func(path string, info walker.FileInfo, err error) error {
 		suite.visited = append(suite.visited, item{
-			path:  path,
-			name:  info.Name(),
-			mode:  info.Mode(),
-			isDir: info.IsDir(),
+			path:    path,
+			name:    info.Name(),
+			mode:    info.Mode(),
+			isDir:   info.IsDir(),
+			size:    info.Size(),
+			modTime: info.ModTime(),
 		})
 		if path == root {
 			return walker.SkipThis
@@ -197,3 +211,82 @@ func (suite *WalkTestSuite) TestWalkNoErrorReturnedIfSkipThisForRoot() {
 		newItem(t, suite.level0Dir, true),
 	}, suite.visited)
 }
+
+func TestWalkRoutesErrorsToWalkFunc(t *testing.T) {
+	for name, walk := range map[string]func(string, walker.WalkFunc) error{
+		"sequential": walker.Walk,
+		"parallel": func(root string, fn walker.WalkFunc) error {
+			return walker.WalkParallel(root, 4, fn)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := newTree(t, 2, 2)
+			defer os.RemoveAll(root)
+
+			removed := filepath.Join(root, "dir0")
+			errs := make(map[string]error)
+			var c collector
+			err := walk(root, walker.Chain(func(path string, info walker.FileInfo, err error) error {
+				if err != nil {
+					c.mutex.Lock()
+					errs[path] = err
+					c.mutex.Unlock()
+					return walker.SkipThis
+				}
+				if path == removed {
+					// directory is removed after visited, such that it could not be read
+					assert.NoError(t, os.RemoveAll(removed))
+				}
+				return nil
+			}, c.walkFunc))
+
+			assert.NoError(t, err)
+			assert.Len(t, errs, 1)
+			assert.ErrorIs(t, errs[removed], os.ErrNotExist)
+			assert.Contains(t, c.paths, removed)
+			assert.Contains(t, c.paths, filepath.Join(root, "dir1", "file0.txt"))
+		})
+	}
+}
+
+func TestWalkStopsWithErrorReturnedForRoutedError(t *testing.T) {
+	root := newTree(t, 1, 2)
+	defer os.RemoveAll(root)
+
+	removed := filepath.Join(root, "dir0")
+	errFailed := fmt.Errorf("failed")
+	err := walker.Walk(root, func(path string, info walker.FileInfo, err error) error {
+		if err != nil {
+			return errFailed
+		}
+		if path == removed {
+			assert.NoError(t, os.RemoveAll(removed))
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, errFailed)
+}
+
+func TestWalkRoutesStatErrorToWalkFunc(t *testing.T) {
+	root := newTree(t, 0, 2)
+	defer os.RemoveAll(root)
+
+	file0 := filepath.Join(root, "file0.txt")
+	file1 := filepath.Join(root, "file1.txt")
+	var errPath string
+	err := walker.Walk(root, func(path string, info walker.FileInfo, err error) error {
+		if err != nil {
+			errPath = path
+			assert.ErrorIs(t, err, os.ErrNotExist)
+			assert.Equal(t, "file1.txt", info.Name())
+			return nil
+		}
+		if path == file0 {
+			// entries are visited in lexical order, so file1 is removed after directory is read
+			assert.NoError(t, os.Remove(file1))
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, file1, errPath)
+}
@@ -0,0 +1,352 @@
+package fzd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/fsnotify/fsnotify"
+	"github.com/horacehylee/fzd/walker"
+)
+
+const (
+	// DefaultDebounce is default duration for collecting file system events before applying them to index
+	DefaultDebounce = 500 * time.Millisecond
+
+	// DefaultRescanInterval is default interval of periodic reindex, when file system watch limit is exceeded
+	DefaultRescanInterval = 10 * time.Minute
+)
+
+// Watcher keeps opened index of Indexer up to date, by applying file system events of its locations
+type Watcher struct {
+	indexer        *Indexer
+	filters        map[string]walker.WalkFunc
+	debounce       time.Duration
+	rescanInterval time.Duration
+	errorHandler   func(error)
+
+	notify   *fsnotify.Watcher
+	pending  map[string]struct{}
+	overflow bool
+
+	// rescanned receives result of rescan in flight, which is nil if there is none
+	rescanned    chan error
+	rescanQueued bool
+}
+
+// WatcherOption for options on watcher setup
+type WatcherOption func(*Watcher)
+
+// WithDebounce specifies duration for collecting file system events before applying them to index as a batch
+func WithDebounce(d time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.debounce = d
+	}
+}
+
+// WithRescanInterval specifies interval of periodic reindex, which is used when file system watch limit is exceeded
+func WithRescanInterval(d time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.rescanInterval = d
+	}
+}
+
+// WithWatchErrorHandler specifies handler for errors that occurred while watching, which do not stop the watcher
+func WithWatchErrorHandler(fn func(error)) WatcherOption {
+	return func(w *Watcher) {
+		w.errorHandler = fn
+	}
+}
+
+// NewWatcher for specified indexer and list of WatcherOptions
+// Indexer should be opened with index containing manifests before running the watcher
+func NewWatcher(indexer *Indexer, options ...WatcherOption) (*Watcher, error) {
+	w := &Watcher{
+		indexer:        indexer,
+		filters:        make(map[string]walker.WalkFunc),
+		debounce:       DefaultDebounce,
+		rescanInterval: DefaultRescanInterval,
+		errorHandler:   func(error) {},
+		pending:        make(map[string]struct{}),
+	}
+	for _, option := range options {
+		option(w)
+	}
+	for path, option := range indexer.locations {
+		filtersWalkFunc, err := newFiltersWalkFunc(path, option)
+		if err != nil {
+			return nil, err
+		}
+		w.filters[filepath.Clean(path)] = filtersWalkFunc
+	}
+	return w, nil
+}
+
+// Run subscribes to every location and applies file system events to opened index, until context is done
+// If file system watch limit is exceeded, it falls back to periodic reindex with specified rescan interval
+// Rescan runs on its own goroutine, such that events are still drained while walking the locations
+func (w *Watcher) Run(ctx context.Context) error {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file system watcher: %w", err)
+	}
+	defer notify.Close()
+	w.notify = notify
+
+	for root := range w.filters {
+		err = w.watchTree(root, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	var flush <-chan time.Time
+	var rescan <-chan time.Time
+	var ticker *time.Ticker
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		if w.overflow && ticker == nil {
+			ticker = time.NewTicker(w.rescanInterval)
+			rescan = ticker.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if w.rescanned != nil {
+				<-w.rescanned
+			}
+			w.flush()
+			return nil
+		case event, ok := <-notify.Events:
+			if !ok {
+				return nil
+			}
+			w.handleEvent(event)
+			if flush == nil {
+				flush = time.After(w.debounce)
+			}
+		case err, ok := <-notify.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// events are lost, such that only full rescan could catch up with the changes
+				w.startRescan(ctx)
+				continue
+			}
+			w.errorHandler(err)
+		case <-flush:
+			flush = nil
+			if w.rescanned != nil {
+				// pending paths are applied once rescan is done, as batches of index are not applied concurrently
+				continue
+			}
+			w.flush()
+		case <-rescan:
+			w.startRescan(ctx)
+		case err := <-w.rescanned:
+			w.rescanned = nil
+			if err != nil && !errors.Is(err, context.Canceled) {
+				w.errorHandler(fmt.Errorf("failed to rescan: %w", err))
+			}
+			if w.rescanQueued {
+				w.rescanQueued = false
+				w.startRescan(ctx)
+				continue
+			}
+			// paths changed while walking could be missed by the rescan
+			if len(w.pending) != 0 && flush == nil {
+				flush = time.After(w.debounce)
+			}
+		}
+	}
+}
+
+func (w *Watcher) handleEvent(event fsnotify.Event) {
+	path := filepath.Clean(event.Name)
+	w.pending[path] = struct{}{}
+	// modification time of parent directory is changed as well
+	w.pending[filepath.Dir(path)] = struct{}{}
+
+	if event.Op&fsnotify.Create != fsnotify.Create {
+		return
+	}
+	info, err := os.Lstat(path)
+	if err != nil || !info.IsDir() {
+		return
+	}
+	// entries could be created before the directory is watched, so they are collected by walking it
+	err = w.watchTree(path, true)
+	if err != nil {
+		w.errorHandler(err)
+	}
+}
+
+// watchTree adds watches for path and its descendant directories, which are not filtered by any of the locations
+// If collect is specified, descendants will be marked as pending to be applied to index
+func (w *Watcher) watchTree(path string, collect bool) error {
+	for root, filtersWalkFunc := range w.filters {
+		if !isWithin(root, path) {
+			continue
+		}
+		fn := walker.Chain(filtersWalkFunc, func(p string, info walker.FileInfo, err error) error {
+			if err != nil {
+				// unreadable entries are reported, such that the rest could still be watched
+				w.errorHandler(fmt.Errorf("failed to watch %v: %w", p, err))
+				return walker.SkipThis
+			}
+			if collect && p != path {
+				w.pending[p] = struct{}{}
+			}
+			if !info.IsDir() || w.overflow {
+				return nil
+			}
+			err = w.notify.Add(p)
+			if isWatchLimitError(err) {
+				w.overflow = true
+				w.errorHandler(fmt.Errorf("watch limit exceeded, falling back to rescan every %v: %w", w.rescanInterval, err))
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to watch %v: %w", p, err)
+			}
+			return nil
+		})
+		err := walker.Walk(path, fn)
+		if err != nil {
+			return fmt.Errorf("failed to traverse path: %w", err)
+		}
+	}
+	return nil
+}
+
+// flush applies pending paths to index as a single batch
+func (w *Watcher) flush() {
+	if len(w.pending) == 0 {
+		return
+	}
+	pending := w.pending
+	w.pending = make(map[string]struct{})
+
+	err := w.indexer.update(func(batches map[string]*bleve.Batch, manifests map[string]manifest) error {
+		included := make(map[string]bool)
+		for path := range pending {
+			err := w.apply(batches, manifests, included, path)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		w.errorHandler(fmt.Errorf("failed to apply file system events: %w", err))
+	}
+}
+
+// apply path to sub-index of its nearest location, which owns the path if it is included by multiple locations
+func (w *Watcher) apply(batches map[string]*bleve.Batch, manifests map[string]manifest, included map[string]bool, path string) error {
+	var root string
+	for location := range w.filters {
+		if isWithin(location, path) && len(location) > len(root) {
+			root = location
+		}
+	}
+	batch, ok := batches[root]
+	if !ok {
+		return nil
+	}
+	m := manifests[root]
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		// descendants are removed along with directory, as they are not notified if directory is moved
+		for p := range m {
+			if isWithin(path, p) {
+				delete(m, p)
+				batch.Delete(p)
+			}
+		}
+		return nil
+	}
+	ok, err = w.includes(root, w.filters[root], included, path)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		delete(m, path)
+		batch.Delete(path)
+		return nil
+	}
+	m[path] = info.ModTime().UnixNano()
+	return newIndexWalkFunc(batch, root)(path, info, nil)
+}
+
+// includes checks if path would be visited while traversing root with filters, by checking all its ancestors
+func (w *Watcher) includes(root string, filtersWalkFunc walker.WalkFunc, included map[string]bool, path string) (bool, error) {
+	key := root + string(os.PathListSeparator) + path
+	if ok, found := included[key]; found {
+		return ok, nil
+	}
+
+	ok := true
+	if path != root {
+		parentOk, err := w.includes(root, filtersWalkFunc, included, filepath.Dir(path))
+		if err != nil {
+			return false, err
+		}
+		ok = parentOk
+	}
+	if ok {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return false, nil
+		}
+		err = filtersWalkFunc(path, info, nil)
+		if errors.Is(err, walker.SkipThis) {
+			ok = false
+		} else if err != nil {
+			return false, err
+		}
+	}
+	included[key] = ok
+	return ok, nil
+}
+
+// startRescan reindexes on its own goroutine, where result is received from rescanned
+// Pending paths are dropped as they are walked by the rescan, and another rescan is queued if one is already in flight
+func (w *Watcher) startRescan(ctx context.Context) {
+	if w.rescanned != nil {
+		w.rescanQueued = true
+		return
+	}
+	w.pending = make(map[string]struct{})
+	rescanned := make(chan error, 1)
+	w.rescanned = rescanned
+	go func() {
+		_, _, err := w.indexer.ReindexContext(ctx, IndexOptions{})
+		rescanned <- err
+	}()
+}
+
+// isWithin checks if path is same as root or is descendant of it
+func isWithin(root string, path string) bool {
+	if root == path {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(root, string(os.PathSeparator))+string(os.PathSeparator))
+}
+
+func isWatchLimitError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
@@ -0,0 +1,220 @@
+package fzd_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/horacehylee/fzd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	watchDebounce = 50 * time.Millisecond
+	watchWaitFor  = 5 * time.Second
+	watchTick     = 50 * time.Millisecond
+)
+
+type WatchTestSuite struct {
+	suite.Suite
+	level0Dir  string
+	level0File string
+	indexesDir string
+	indexer    *fzd.Indexer
+	cancel     context.CancelFunc
+	done       chan error
+}
+
+func TestWatchTestSuite(t *testing.T) {
+	suite.Run(t, new(WatchTestSuite))
+}
+
+func (suite *WatchTestSuite) SetupTest() {
+	t := suite.T()
+
+	indexesDir, err := os.MkdirTemp("", "testWatchSuiteIndexes")
+	assert.NoError(t, err)
+	suite.indexesDir = indexesDir
+
+	dir, err := os.MkdirTemp("", "testWatchSuite")
+	assert.NoError(t, err)
+	suite.level0Dir = dir
+
+	suite.level0File = filepath.Join(suite.level0Dir, "level0.txt")
+	err = os.WriteFile(suite.level0File, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	indexer, err := fzd.NewIndexer(suite.indexesDir, fzd.WithLocation(suite.level0Dir, fzd.LocationOption{
+		Ignores: []interface{}{"ignored*"},
+	}))
+	assert.NoError(t, err)
+	suite.indexer = indexer
+
+	name, err := indexer.Index()
+	assert.NoError(t, err)
+	err = indexer.OpenAndSwap(name)
+	assert.NoError(t, err)
+
+	w, err := fzd.NewWatcher(indexer,
+		fzd.WithDebounce(watchDebounce),
+		fzd.WithWatchErrorHandler(func(err error) {
+			assert.NoError(t, err)
+		}),
+	)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.cancel = cancel
+	suite.done = make(chan error)
+	go func() {
+		suite.done <- w.Run(ctx)
+	}()
+
+	// wait for watches to be added
+	time.Sleep(watchDebounce)
+}
+
+func (suite *WatchTestSuite) TearDownTest() {
+	t := suite.T()
+
+	suite.cancel()
+	assert.NoError(t, <-suite.done)
+
+	err := suite.indexer.Close()
+	assert.NoError(t, err)
+
+	err = os.RemoveAll(suite.level0Dir)
+	assert.NoError(t, err)
+	err = os.RemoveAll(suite.indexesDir)
+	assert.NoError(t, err)
+}
+
+func (suite *WatchTestSuite) TestWatchCreatedFile() {
+	t := suite.T()
+
+	file := filepath.Join(suite.level0Dir, "created.txt")
+	err := os.WriteFile(file, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	suite.assertDocCount(3)
+	suite.assertSearchContains("created", file)
+}
+
+func (suite *WatchTestSuite) TestWatchIgnoredFile() {
+	t := suite.T()
+
+	ignored := filepath.Join(suite.level0Dir, "ignored.txt")
+	err := os.WriteFile(ignored, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	file := filepath.Join(suite.level0Dir, "created.txt")
+	err = os.WriteFile(file, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	suite.assertDocCount(3)
+}
+
+func (suite *WatchTestSuite) TestWatchRemovedFile() {
+	t := suite.T()
+
+	err := os.Remove(suite.level0File)
+	assert.NoError(t, err)
+
+	suite.assertDocCount(1)
+}
+
+func (suite *WatchTestSuite) TestWatchCreatedDirectoryRecursively() {
+	t := suite.T()
+
+	dir := filepath.Join(suite.level0Dir, "level1")
+	err := os.MkdirAll(filepath.Join(dir, "level2"), fileMode)
+	assert.NoError(t, err)
+
+	file := filepath.Join(dir, "level2", "level2.txt")
+	err = os.WriteFile(file, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	suite.assertDocCount(5)
+	suite.assertSearchContains("level2", file)
+
+	nested := filepath.Join(dir, "level2", "nested.txt")
+	err = os.WriteFile(nested, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	suite.assertDocCount(6)
+}
+
+func (suite *WatchTestSuite) TestWatchRenamedDirectory() {
+	t := suite.T()
+
+	dir := filepath.Join(suite.level0Dir, "level1")
+	err := os.Mkdir(dir, fileMode)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "level1.txt"), []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	suite.assertDocCount(4)
+
+	renamed := filepath.Join(suite.level0Dir, "renamed")
+	err = os.Rename(dir, renamed)
+	assert.NoError(t, err)
+
+	suite.assertSearchEventually("level1", filepath.Join(renamed, "level1.txt"), filepath.Join(dir, "level1.txt"))
+	suite.assertDocCount(4)
+}
+
+func (suite *WatchTestSuite) TestReindexAfterWatch() {
+	t := suite.T()
+
+	file := filepath.Join(suite.level0Dir, "created.txt")
+	err := os.WriteFile(file, []byte("content"), fileMode)
+	assert.NoError(t, err)
+
+	suite.assertDocCount(3)
+
+	delta, err := suite.indexer.Reindex()
+	assert.NoError(t, err)
+	assert.Equal(t, fzd.Delta{}, delta, "watched changes should be tracked by manifests")
+}
+
+func (suite *WatchTestSuite) assertDocCount(expected uint64) {
+	assert.Eventually(suite.T(), func() bool {
+		count, err := suite.indexer.DocCount()
+		return err == nil && count == expected
+	}, watchWaitFor, watchTick)
+}
+
+func (suite *WatchTestSuite) assertSearchEventually(term string, included string, excluded string) {
+	assert.Eventually(suite.T(), func() bool {
+		res, err := suite.indexer.Search(term)
+		if err != nil {
+			return false
+		}
+		found := false
+		for _, h := range res.Hits {
+			if h.ID == excluded {
+				return false
+			}
+			if h.ID == included {
+				found = true
+			}
+		}
+		return found
+	}, watchWaitFor, watchTick)
+}
+
+func (suite *WatchTestSuite) assertSearchContains(term string, path string) {
+	t := suite.T()
+
+	res, err := suite.indexer.Search(term)
+	assert.NoError(t, err)
+
+	var hits []string
+	for _, h := range res.Hits {
+		hits = append(hits, h.ID)
+	}
+	assert.Contains(t, hits, path)
+}
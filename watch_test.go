@@ -0,0 +1,54 @@
+package fzd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsWithin(t *testing.T) {
+	root := filepath.Clean("/level0")
+
+	assert.True(t, isWithin(root, root))
+	assert.True(t, isWithin(root, filepath.Join(root, "level0.txt")))
+	assert.True(t, isWithin(root, filepath.Join(root, "level1", "level1.txt")))
+	assert.False(t, isWithin(root, filepath.Clean("/level0.txt")))
+	assert.False(t, isWithin(root, filepath.Clean("/level00/level0.txt")))
+	assert.False(t, isWithin(filepath.Join(root, "level1"), root))
+}
+
+func TestWatcherStartRescanIsSingleFlight(t *testing.T) {
+	root := t.TempDir()
+	indexer, err := NewIndexer(t.TempDir(), WithLocation(root, LocationOption{}))
+	assert.NoError(t, err)
+	defer indexer.Close()
+	name, err := indexer.Index()
+	assert.NoError(t, err)
+	err = indexer.OpenAndSwap(name)
+	assert.NoError(t, err)
+
+	w, err := NewWatcher(indexer)
+	assert.NoError(t, err)
+	file := filepath.Join(root, "rescanned.txt")
+	err = os.WriteFile(file, []byte("content"), 0600)
+	assert.NoError(t, err)
+	w.pending[file] = struct{}{}
+
+	// pending paths are dropped, as they are walked by the rescan
+	w.startRescan(context.Background())
+	assert.Empty(t, w.pending)
+	rescanned := w.rescanned
+	assert.NotNil(t, rescanned)
+
+	w.startRescan(context.Background())
+	assert.True(t, w.rescanQueued)
+	assert.Equal(t, rescanned, w.rescanned, "rescan in flight is not started again")
+
+	assert.NoError(t, <-rescanned)
+	count, err := indexer.DocCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+}